@@ -0,0 +1,205 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+// config-policy-eval evaluates ConfigurationPolicies against a directory of Kubernetes manifests, without a
+// cluster, so that the violations of rendered manifests are found before they are deployed
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/open-cluster-management/config-policy-controller/pkg/evaluation"
+	"github.com/spf13/pflag"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// the exit codes of the command
+const (
+	exitCompliant    = 0
+	exitNonCompliant = 1
+	exitError        = 2
+)
+
+func main() {
+	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
+
+	var policyFiles []string
+	var manifestsDir, output string
+	pflag.StringSliceVarP(&policyFiles, "policy", "p", nil,
+		"A file with ConfigurationPolicies, or Policies wrapping them, can be repeated")
+	pflag.StringVarP(&manifestsDir, "manifests", "m", "",
+		"The directory of the YAML or JSON manifests the policies are evaluated against, read recursively")
+	pflag.StringVarP(&output, "output", "o", "text", "The output format: text, json or yaml")
+	pflag.Parse()
+
+	if len(policyFiles) == 0 || manifestsDir == "" {
+		fmt.Fprintln(os.Stderr, "usage: config-policy-eval --policy <file> [--policy <file>...] --manifests <dir>")
+		pflag.PrintDefaults()
+		os.Exit(exitError)
+	}
+	policies := []*policyv1.ConfigurationPolicy{}
+	for _, file := range policyFiles {
+		filePolicies, err := readPolicies(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading the policies of %v: %v\n", file, err)
+			os.Exit(exitError)
+		}
+		policies = append(policies, filePolicies...)
+	}
+	objects, err := readManifests(manifestsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading the manifests of %v: %v\n", manifestsDir, err)
+		os.Exit(exitError)
+	}
+
+	results := []evaluation.Result{}
+	exitCode := exitCompliant
+	for _, plc := range policies {
+		result := evaluation.EvaluateObjects(plc, objects)
+		if result.ComplianceState != policyv1.Compliant {
+			exitCode = exitNonCompliant
+		}
+		results = append(results, result)
+	}
+	if err := printResults(os.Stdout, results, output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error printing the results: %v\n", err)
+		os.Exit(exitError)
+	}
+	os.Exit(exitCode)
+}
+
+// readDocuments returns the JSON of each YAML or JSON document of a file
+func readDocuments(file string) ([]runtime.RawExtension, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	documents := []runtime.RawExtension{}
+	decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(content), 4096)
+	for {
+		document := runtime.RawExtension{}
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				return documents, nil
+			}
+			return nil, err
+		}
+		// skip the empty documents
+		if len(document.Raw) > 0 && string(document.Raw) != "null" {
+			documents = append(documents, document)
+		}
+	}
+}
+
+// readPolicies returns the ConfigurationPolicies of a file, including the ones in the policy-templates of
+// the Policies of the file
+func readPolicies(file string) ([]*policyv1.ConfigurationPolicy, error) {
+	documents, err := readDocuments(file)
+	if err != nil {
+		return nil, err
+	}
+	policies := []*policyv1.ConfigurationPolicy{}
+	for len(documents) > 0 {
+		document := documents[0]
+		documents = documents[1:]
+		object := unstructured.Unstructured{}
+		if err := object.UnmarshalJSON(document.Raw); err != nil {
+			return nil, err
+		}
+		switch object.GetKind() {
+		case "ConfigurationPolicy":
+			plc := &policyv1.ConfigurationPolicy{}
+			if err := json.Unmarshal(document.Raw, plc); err != nil {
+				return nil, err
+			}
+			policies = append(policies, plc)
+		case "Policy":
+			policyTemplates, _, _ := unstructured.NestedSlice(object.Object, "spec", "policy-templates")
+			for _, policyTemplate := range policyTemplates {
+				definition, _, _ := unstructured.NestedMap(policyTemplate.(map[string]interface{}),
+					"objectDefinition")
+				raw, err := json.Marshal(definition)
+				if err != nil {
+					return nil, err
+				}
+				documents = append(documents, runtime.RawExtension{Raw: raw})
+			}
+		}
+	}
+	return policies, nil
+}
+
+// readManifests returns the objects of the YAML and JSON files of a directory and of its subdirectories
+func readManifests(dir string) ([]unstructured.Unstructured, error) {
+	objects := []unstructured.Unstructured{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if info.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			return nil
+		}
+		documents, err := readDocuments(path)
+		if err != nil {
+			return fmt.Errorf("%v: %w", path, err)
+		}
+		for _, document := range documents {
+			object := unstructured.Unstructured{}
+			if err := object.UnmarshalJSON(document.Raw); err != nil {
+				return fmt.Errorf("%v: %w", path, err)
+			}
+			if object.IsList() {
+				list, _ := object.ToList()
+				objects = append(objects, list.Items...)
+				continue
+			}
+			objects = append(objects, object)
+		}
+		return nil
+	})
+	return objects, err
+}
+
+// printResults prints the compliance of the evaluated policies and of their templates
+func printResults(out io.Writer, results []evaluation.Result, output string) error {
+	switch output {
+	case "json":
+		content, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(out, string(content))
+		return err
+	case "yaml":
+		content, err := yaml.Marshal(results)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(out, string(content))
+		return err
+	case "text":
+		for _, result := range results {
+			fmt.Fprintf(out, "%v: %v\n", result.Name, result.ComplianceState)
+			for _, template := range result.Templates {
+				fmt.Fprintf(out, "  object-templates[%v]: %v: %v\n", template.Index, template.ComplianceState,
+					template.Message)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown output format %v", output)
+}
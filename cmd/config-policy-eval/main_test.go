@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/open-cluster-management/config-policy-controller/pkg/evaluation"
+	"github.com/stretchr/testify/assert"
+)
+
+const policies = `apiVersion: policy.open-cluster-management.io/v1
+kind: ConfigurationPolicy
+metadata:
+  name: policy-configmap
+spec:
+  remediationAction: inform
+  object-templates:
+    - complianceType: musthave
+      objectDefinition:
+        apiVersion: v1
+        kind: ConfigMap
+        metadata:
+          name: config
+          namespace: default
+---
+apiVersion: policy.open-cluster-management.io/v1
+kind: Policy
+metadata:
+  name: parent
+spec:
+  policy-templates:
+    - objectDefinition:
+        apiVersion: policy.open-cluster-management.io/v1
+        kind: ConfigurationPolicy
+        metadata:
+          name: policy-wrapped
+        spec:
+          remediationAction: inform
+`
+
+const manifests = `apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: config
+      namespace: default
+  - apiVersion: v1
+    kind: Secret
+    metadata:
+      name: secret
+      namespace: default
+---
+`
+
+func TestReadPoliciesAndManifests(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-policy-eval")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "policies.yaml"), []byte(policies), 0600))
+	assert.Nil(t, os.Mkdir(filepath.Join(dir, "manifests"), 0700))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "manifests", "list.yml"), []byte(manifests), 0600))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "manifests", "README.md"), []byte("# not a manifest"), 0600))
+
+	plcs, err := readPolicies(filepath.Join(dir, "policies.yaml"))
+	assert.Nil(t, err)
+	assert.Len(t, plcs, 2)
+	assert.Equal(t, "policy-configmap", plcs[0].GetName())
+	assert.Len(t, plcs[0].Spec.ObjectTemplates, 1)
+	assert.Equal(t, "policy-wrapped", plcs[1].GetName())
+
+	objects, err := readManifests(filepath.Join(dir, "manifests"))
+	assert.Nil(t, err)
+	assert.Len(t, objects, 2)
+	assert.Equal(t, "ConfigMap", objects[0].GetKind())
+	assert.Equal(t, "Secret", objects[1].GetKind())
+}
+
+func TestPrintResults(t *testing.T) {
+	plc := &policyv1.ConfigurationPolicy{}
+	plc.SetName("policy-configmap")
+	plc.Status.ComplianceState = policyv1.NonCompliant
+	plc.Status.CompliancyDetails = []policyv1.TemplateStatus{{
+		ComplianceState: policyv1.NonCompliant,
+		Conditions: []policyv1.Condition{{
+			Reason:  "K8s does not have a `must have` object",
+			Message: "configmaps not found: [config] in namespace default missing",
+		}},
+	}}
+
+	out := &strings.Builder{}
+	assert.Nil(t, printResults(out, []evaluation.Result{evaluation.NewResult(plc)}, "text"))
+	assert.Equal(t, "policy-configmap: NonCompliant\n  object-templates[0]: NonCompliant: configmaps not found: "+
+		"[config] in namespace default missing\n", out.String())
+
+	out.Reset()
+	assert.Nil(t, printResults(out, []evaluation.Result{evaluation.NewResult(plc)}, "json"))
+	assert.Contains(t, out.String(), `"reason": "K8s does not have a `+"`must have`"+` object"`)
+
+	assert.NotNil(t, printResults(out, []evaluation.Result{evaluation.NewResult(plc)}, "table"))
+}
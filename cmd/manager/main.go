@@ -16,11 +16,14 @@ import (
 	"github.com/open-cluster-management/addon-framework/pkg/lease"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
+	"k8s.io/client-go/rest"
 
 	"github.com/open-cluster-management/config-policy-controller/pkg/apis"
 	"github.com/open-cluster-management/config-policy-controller/pkg/common"
+	"github.com/open-cluster-management/config-policy-controller/pkg/common/templates"
 	"github.com/open-cluster-management/config-policy-controller/pkg/controller"
 	policyStatusHandler "github.com/open-cluster-management/config-policy-controller/pkg/controller/configurationpolicy"
+	"github.com/open-cluster-management/config-policy-controller/pkg/webhook"
 	"github.com/open-cluster-management/config-policy-controller/version"
 
 	"github.com/operator-framework/operator-sdk/pkg/k8sutil"
@@ -53,10 +56,26 @@ func main() {
 	pflag.CommandLine.AddGoFlagSet(flag.CommandLine)
 
 	var eventOnParent, clusterName, hubConfigSecretNs, hubConfigSecretName string
-	var frequency uint
-	var enableLease bool
+	var frequency, evaluationConcurrency, complianceHistorySize uint
+	var enableLease, watchResources, enableWebhook, restrictTemplateNamespaces bool
+	var webhookPort int
+	var templateAllowedNamespaces []string
 	pflag.UintVar(&frequency, "update-frequency", 10,
-		"The status update frequency (in seconds) of a mutation policy")
+		"The frequency (in seconds) at which all policies are re-evaluated, regardless of changes to their objects")
+	pflag.UintVar(&evaluationConcurrency, "evaluation-concurrency", 2,
+		"The maximum number of policies evaluated in parallel")
+	pflag.UintVar(&complianceHistorySize, "compliance-history-size", 10,
+		"The number of compliance changes kept in the status of each object template, 0 disables the history")
+	pflag.BoolVar(&watchResources, "watch-resources", true,
+		"If enabled, policies are also evaluated as soon as an object they refer to changes")
+	pflag.BoolVar(&enableWebhook, "enable-webhook", false,
+		"If enabled, the manager serves the webhook validating the configuration policies")
+	pflag.IntVar(&webhookPort, "webhook-port", 9443,
+		"The port the validating webhook is served at, with the certificate in /tmp/k8s-webhook-server/serving-certs")
+	pflag.BoolVar(&restrictTemplateNamespaces, "restrict-template-namespaces", false,
+		"If enabled, the templates only read from the namespace of their policy and from the allowed namespaces")
+	pflag.StringSliceVar(&templateAllowedNamespaces, "template-allowed-namespaces", nil,
+		"The namespaces all the templates can read from when they are restricted, wildcards like kube-* are supported")
 	pflag.StringVar(&eventOnParent, "parent-event", "ifpresent",
 		"to also send status events on parent policy. options are: yes/no/ifpresent")
 	pflag.BoolVar(&enableLease, "enable-lease", false,
@@ -96,6 +115,7 @@ func main() {
 	options := manager.Options{
 		Namespace:          namespace,
 		MetricsBindAddress: fmt.Sprintf("%s:%d", metricsHost, metricsPort),
+		Port:               webhookPort,
 	}
 
 	if strings.Contains(namespace, ",") {
@@ -124,6 +144,14 @@ func main() {
 		os.Exit(1)
 	}
 
+	if enableWebhook {
+		log.Info("Registering the configuration policy validating webhook")
+		if err := webhook.AddToManager(mgr); err != nil {
+			log.Error(err, "")
+			os.Exit(1)
+		}
+	}
+
 	// Initialize some variables
 	client, err := kubernetes.NewForConfig(cfg)
 	if err != nil {
@@ -131,10 +159,21 @@ func main() {
 	}
 	var generatedClient kubernetes.Interface = kubernetes.NewForConfigOrDie(mgr.GetConfig())
 	common.Initialize(&generatedClient, cfg)
+	// the hub template functions read from the namespace of the cluster on the hub
+	templates.InitializeHubClient(func() (*rest.Config, error) {
+		return common.LoadHubConfig(hubConfigSecretNs, hubConfigSecretName)
+	}, clusterName)
+	templates.SetNamespaceAccess(restrictTemplateNamespaces, templateAllowedNamespaces)
 
 	policyStatusHandler.Initialize(cfg, client, &generatedClient, mgr, namespace, eventOnParent)
+	policyStatusHandler.InitializeComplianceHistory(complianceHistorySize)
+	if watchResources {
+		if err := policyStatusHandler.InitializeObjectWatcher(cfg); err != nil {
+			log.Error(err, "Failed to watch the policy objects, relying on the periodic evaluation only")
+		}
+	}
 	// PeriodicallyExecConfigPolicies is the go-routine that periodically checks the policies
-	go policyStatusHandler.PeriodicallyExecConfigPolicies(frequency, false)
+	go policyStatusHandler.PeriodicallyExecConfigPolicies(frequency, evaluationConcurrency, false)
 
 	if enableLease {
 		operatorNs, err := k8sutil.GetOperatorNamespace()
@@ -4,24 +4,51 @@
 package v1
 
 import (
+	"errors"
+	"strings"
+	"time"
+
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
-// RemediationAction : enforce or inform
+// RemediationAction : enforce, inform or dryrun
 type RemediationAction string
 
-// Severity : low, medium or high
+// Severity : low, medium, high or critical
+// +kubebuilder:validation:Enum=low;medium;high;critical
 type Severity string
 
+const (
+	// SeverityLow is the severity of a policy with a cosmetic impact
+	SeverityLow Severity = "low"
+
+	// SeverityMedium is the severity of a policy with a moderate impact
+	SeverityMedium Severity = "medium"
+
+	// SeverityHigh is the severity of a policy whose violations are reported as warnings
+	SeverityHigh Severity = "high"
+
+	// SeverityCritical is the severity of a policy whose violations must be addressed first
+	SeverityCritical Severity = "critical"
+)
+
+// IsHigh returns true for the high and critical severities
+func (s Severity) IsHigh() bool {
+	return s == SeverityHigh || s == SeverityCritical
+}
+
 const (
 	// Enforce is an remediationAction to make changes
 	Enforce RemediationAction = "Enforce"
 
 	// Inform is an remediationAction to only inform
 	Inform RemediationAction = "Inform"
+
+	// DryRun is an remediationAction to report the changes enforce would make, without persisting them
+	DryRun RemediationAction = "DryRun"
 )
 
 // ComplianceState shows the state of enforcement
@@ -59,16 +86,103 @@ type Condition struct {
 type Target struct {
 	Include []string `json:"include,omitempty"`
 	Exclude []string `json:"exclude,omitempty"`
+	// MatchLabels selects namespaces by their labels, combined with the include/exclude lists
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+	// MatchExpressions selects namespaces by label expressions, combined with the include/exclude lists
+	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+}
+
+// ObjectSelector selects the objects compared with an object template whose object definition has no name, the
+// labels and fields are selected by the API server
+type ObjectSelector struct {
+	// MatchLabels selects the objects by their labels
+	MatchLabels map[string]string `json:"matchLabels,omitempty"`
+	// MatchExpressions selects the objects by label expressions, combined with matchLabels
+	MatchExpressions []metav1.LabelSelectorRequirement `json:"matchExpressions,omitempty"`
+	// FieldSelector selects the objects by their fields, such as "status.phase=Running", the supported fields
+	// depend on the kind
+	FieldSelector string `json:"fieldSelector,omitempty"`
+	// Owner selects the objects with an owner reference to this owner
+	Owner *OwnerSelector `json:"owner,omitempty"`
+}
+
+// OwnerSelector matches the owner references of an object, the name is optional
+type OwnerSelector struct {
+	Kind string `json:"kind"`
+	Name string `json:"name,omitempty"`
+}
+
+// EvaluationInterval configures the minimum time between two evaluations of the policy, depending on its
+// compliance. A duration such as "10s" or "1h", or "never" to stop evaluating the policy once it is in that state.
+// When unset, the policy is evaluated on every resync of the controller. A change to the objects of a compliant
+// policy evaluates it before its compliant interval elapses, unless the interval is "never".
+type EvaluationInterval struct {
+	// +kubebuilder:validation:Pattern=`^(?:(?:[0-9]+(?:\.[0-9]+)?(?:h|m|s|ms|us|ns))+|never)$`
+	Compliant string `json:"compliant,omitempty"`
+	// +kubebuilder:validation:Pattern=`^(?:(?:[0-9]+(?:\.[0-9]+)?(?:h|m|s|ms|us|ns))+|never)$`
+	NonCompliant string `json:"noncompliant,omitempty"`
+}
+
+// ErrIsNever is returned when an evaluation interval is set to "never"
+var ErrIsNever = errors.New("the interval is set to never")
+
+// GetCompliantInterval parses the interval of a compliant policy, 0 is returned when it is unset
+func (e EvaluationInterval) GetCompliantInterval() (time.Duration, error) {
+	return parseInterval(e.Compliant)
+}
+
+// GetNonCompliantInterval parses the interval of a noncompliant policy, 0 is returned when it is unset
+func (e EvaluationInterval) GetNonCompliantInterval() (time.Duration, error) {
+	return parseInterval(e.NonCompliant)
+}
+
+func parseInterval(interval string) (time.Duration, error) {
+	if interval == "" {
+		return 0, nil
+	}
+	if strings.ToLower(interval) == "never" {
+		return 0, ErrIsNever
+	}
+	duration, err := time.ParseDuration(interval)
+	if err != nil {
+		return 0, err
+	}
+	if duration < 0 {
+		return 0, errors.New("the interval must not be negative")
+	}
+	return duration, nil
 }
 
+// PruneObjectBehavior : None, DeleteIfCreated or DeleteAll
+// +kubebuilder:validation:Enum=None;DeleteIfCreated;DeleteAll
+type PruneObjectBehavior string
+
+const (
+	// PruneNone keeps the objects of the policy when it is deleted
+	PruneNone PruneObjectBehavior = "None"
+
+	// PruneDeleteIfCreated deletes the objects the policy created when it is deleted
+	PruneDeleteIfCreated PruneObjectBehavior = "DeleteIfCreated"
+
+	// PruneDeleteAll deletes all the objects the policy must have when it is deleted
+	PruneDeleteAll PruneObjectBehavior = "DeleteAll"
+)
+
 // ConfigurationPolicySpec defines the desired state of ConfigurationPolicy
 // +k8s:openapi-gen=true
 type ConfigurationPolicySpec struct {
-	Severity          Severity          `json:"severity,omitempty"`          //low, medium, high
-	RemediationAction RemediationAction `json:"remediationAction,omitempty"` //enforce, inform
+	Severity          Severity          `json:"severity,omitempty"`          //low, medium, high, critical
+	RemediationAction RemediationAction `json:"remediationAction,omitempty"` //enforce, inform, dryrun
 	NamespaceSelector Target            `json:"namespaceSelector,omitempty"`
-	LabelSelector     map[string]string `json:"labelSelector,omitempty"`
+	LabelSelector     map[string]string `json:"labelSelector,omitempty"` //namespace labels, ANDed with namespaceSelector
 	ObjectTemplates   []*ObjectTemplate `json:"object-templates,omitempty"`
+	// ObjectTemplatesRaw is a template resolving to the YAML list of the object templates, it is used instead of
+	// ObjectTemplates so that the number of object templates can vary, for example with a range over a lookup
+	ObjectTemplatesRaw string `json:"object-templates-raw,omitempty"`
+	// EvaluationInterval sets how often the policy is evaluated when it is compliant and noncompliant
+	EvaluationInterval EvaluationInterval `json:"evaluationInterval,omitempty"`
+	// PruneObjectBehavior sets which objects of an enforced policy are deleted along with the policy
+	PruneObjectBehavior PruneObjectBehavior `json:"pruneObjectBehavior,omitempty"`
 }
 
 // ObjectTemplate describes how an object should look
@@ -79,6 +193,92 @@ type ObjectTemplate struct {
 	// ObjectDefinition defines required fields for the object
 	// +kubebuilder:pruning:PreserveUnknownFields
 	ObjectDefinition runtime.RawExtension `json:"objectDefinition,omitempty"`
+
+	// ForceOwnership takes over the fields of the template managed by other field managers when it is enforced,
+	// instead of reporting the conflict
+	ForceOwnership bool `json:"forceOwnership,omitempty"`
+
+	// FieldLevel makes a mustnothave template forbid the fields of its object definition rather than the whole
+	// object, enforcing it removes those fields and keeps the object
+	FieldLevel bool `json:"fieldLevel,omitempty"`
+
+	// NamespaceSelector selects the namespaces of this template instead of the namespaceSelector and
+	// labelSelector of the policy, a namespace set in the object definition still takes precedence
+	NamespaceSelector *Target `json:"namespaceSelector,omitempty"`
+
+	// ObjectSelector restricts the objects compared with the template when its object definition has no name
+	ObjectSelector *ObjectSelector `json:"objectSelector,omitempty"`
+
+	// Assertions are checked on each object found for a musthave or mustonlyhave template, an object failing one
+	// of them is noncompliant
+	Assertions []Assertion `json:"assertions,omitempty"`
+}
+
+// AssertionOperator : Equals, NotEquals, GreaterThan, GreaterThanOrEqual, LessThan, LessThanOrEqual, Exists,
+// DoesNotExist, HasPrefix, HasSuffix, Contains or Matches
+type AssertionOperator string
+
+const (
+	// AssertEquals checks that the value is equal to the assertion value
+	AssertEquals AssertionOperator = "Equals"
+
+	// AssertNotEquals checks that the value is not equal to the assertion value
+	AssertNotEquals AssertionOperator = "NotEquals"
+
+	// AssertGreaterThan checks that the number or quantity is greater than the assertion value
+	AssertGreaterThan AssertionOperator = "GreaterThan"
+
+	// AssertGreaterThanOrEqual checks that the number or quantity is greater than or equal to the assertion value
+	AssertGreaterThanOrEqual AssertionOperator = "GreaterThanOrEqual"
+
+	// AssertLessThan checks that the number or quantity is less than the assertion value
+	AssertLessThan AssertionOperator = "LessThan"
+
+	// AssertLessThanOrEqual checks that the number or quantity is less than or equal to the assertion value
+	AssertLessThanOrEqual AssertionOperator = "LessThanOrEqual"
+
+	// AssertExists checks that the field is set
+	AssertExists AssertionOperator = "Exists"
+
+	// AssertDoesNotExist checks that the field is not set
+	AssertDoesNotExist AssertionOperator = "DoesNotExist"
+
+	// AssertHasPrefix checks that the value starts with the assertion value
+	AssertHasPrefix AssertionOperator = "HasPrefix"
+
+	// AssertHasSuffix checks that the value ends with the assertion value
+	AssertHasSuffix AssertionOperator = "HasSuffix"
+
+	// AssertContains checks that the value contains the assertion value
+	AssertContains AssertionOperator = "Contains"
+
+	// AssertMatches checks that the value matches the regular expression of the assertion value
+	AssertMatches AssertionOperator = "Matches"
+)
+
+// Assertion is a check of the fields of the objects of an object template. The path is a JSONPath expression
+// such as ".spec.containers[*].resources.limits.memory", and every value it selects must satisfy the operator.
+type Assertion struct {
+	// Path of the fields, with [*] selecting all the items of a list or map and [n] one item of a list
+	Path string `json:"path"`
+	// Operator comparing the fields with the value
+	Operator AssertionOperator `json:"operator"`
+	// Value compared with the fields, the numbers and quantities such as "512Mi" are compared by value
+	Value string `json:"value,omitempty"`
+}
+
+// AssertionFailure is an assertion a field of an object does not satisfy
+type AssertionFailure struct {
+	// Path of the field, with the items selected by [*] replaced by their index or key
+	Path string `json:"path"`
+	// Operator of the assertion
+	Operator AssertionOperator `json:"operator"`
+	// Value of the assertion
+	Value string `json:"value,omitempty"`
+	// Actual is the JSON value of the field, unset when the field is missing, the values of secrets are redacted
+	Actual string `json:"actual,omitempty"`
+	// Message explains why the value could not be compared
+	Message string `json:"message,omitempty"`
 }
 
 // ConfigurationPolicyStatus is the status for a Policy resource
@@ -86,6 +286,7 @@ type ConfigurationPolicyStatus struct {
 	ComplianceState   ComplianceState  `json:"compliant,omitempty"`         // Compliant, NonCompliant, UnkownCompliancy
 	CompliancyDetails []TemplateStatus `json:"compliancyDetails,omitempty"` // reason for non-compliancy
 	RelatedObjects    []RelatedObject  `json:"relatedObjects,omitempty"`    // List of resources processed by the policy
+	Severity          Severity         `json:"severity,omitempty"`          // copied from the spec
 }
 
 // CompliancePerClusterStatus contains aggregate status of other policies in cluster
@@ -151,6 +352,22 @@ type TemplateStatus struct {
 	Conditions []Condition `json:"conditions,omitempty"`
 
 	Validity Validity `json:"Validity,omitempty"` // a template can be invalid if it has conflicting roles
+
+	// History lists the latest compliance changes of the template, the most recent first
+	// +optional
+	History []ComplianceHistory `json:"history,omitempty"`
+}
+
+// ComplianceHistory is a change of the compliance of an object template
+type ComplianceHistory struct {
+	// Timestamp of the change
+	Timestamp metav1.Time `json:"timestamp,omitempty"`
+	// ComplianceState of the template after the change
+	ComplianceState ComplianceState `json:"compliant,omitempty"`
+	// Reason of the condition reporting the change
+	Reason string `json:"reason,omitempty"`
+	// Message of the condition reporting the change
+	Message string `json:"message,omitempty"`
 }
 
 // Validity describes if it is valid or not
@@ -190,6 +407,31 @@ type RelatedObject struct {
 	Compliant string `json:"compliant,omitempty"`
 	//
 	Reason string `json:"reason,omitempty"`
+	// Differences lists the fields of a noncompliant object that do not match the policy,
+	// the values of secrets are redacted
+	Differences []FieldDifference `json:"differences,omitempty"`
+	// FailedAssertions lists the assertions of the object template the object fails
+	FailedAssertions []AssertionFailure `json:"failedAssertions,omitempty"`
+	// Properties tracked by the policy for the object
+	Properties *ObjectProperties `json:"properties,omitempty"`
+}
+
+// ObjectProperties are the properties of a related object tracked by the policy
+type ObjectProperties struct {
+	// CreatedByPolicy is true when the policy created the object
+	CreatedByPolicy bool `json:"createdByPolicy,omitempty"`
+	// UID of the object the policy created, a recreated object with the same name is not pruned
+	UID string `json:"uid,omitempty"`
+}
+
+// FieldDifference is a field of an object that does not match the policy
+type FieldDifference struct {
+	// Path of the field, e.g. spec.replicas
+	Path string `json:"path"`
+	// Expected is the JSON value the policy expects, unset when the field must not be set
+	Expected string `json:"expected,omitempty"`
+	// Actual is the JSON value of the field on the cluster, unset when the field is missing
+	Actual string `json:"actual,omitempty"`
 }
 
 // ObjectResource is an object identified by the policy as a resource that needs to be validated.
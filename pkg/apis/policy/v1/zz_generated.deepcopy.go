@@ -5,9 +5,59 @@
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Assertion) DeepCopyInto(out *Assertion) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Assertion.
+func (in *Assertion) DeepCopy() *Assertion {
+	if in == nil {
+		return nil
+	}
+	out := new(Assertion)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AssertionFailure) DeepCopyInto(out *AssertionFailure) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AssertionFailure.
+func (in *AssertionFailure) DeepCopy() *AssertionFailure {
+	if in == nil {
+		return nil
+	}
+	out := new(AssertionFailure)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComplianceHistory) DeepCopyInto(out *ComplianceHistory) {
+	*out = *in
+	in.Timestamp.DeepCopyInto(&out.Timestamp)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComplianceHistory.
+func (in *ComplianceHistory) DeepCopy() *ComplianceHistory {
+	if in == nil {
+		return nil
+	}
+	out := new(ComplianceHistory)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in ComplianceMap) DeepCopyInto(out *ComplianceMap) {
 	{
@@ -169,6 +219,7 @@ func (in *ConfigurationPolicySpec) DeepCopyInto(out *ConfigurationPolicySpec) {
 			}
 		}
 	}
+	out.EvaluationInterval = in.EvaluationInterval
 	return
 }
 
@@ -195,7 +246,9 @@ func (in *ConfigurationPolicyStatus) DeepCopyInto(out *ConfigurationPolicyStatus
 	if in.RelatedObjects != nil {
 		in, out := &in.RelatedObjects, &out.RelatedObjects
 		*out = make([]RelatedObject, len(*in))
-		copy(*out, *in)
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
 	return
 }
@@ -210,6 +263,38 @@ func (in *ConfigurationPolicyStatus) DeepCopy() *ConfigurationPolicyStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EvaluationInterval) DeepCopyInto(out *EvaluationInterval) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvaluationInterval.
+func (in *EvaluationInterval) DeepCopy() *EvaluationInterval {
+	if in == nil {
+		return nil
+	}
+	out := new(EvaluationInterval)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FieldDifference) DeepCopyInto(out *FieldDifference) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FieldDifference.
+func (in *FieldDifference) DeepCopy() *FieldDifference {
+	if in == nil {
+		return nil
+	}
+	out := new(FieldDifference)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectMetadata) DeepCopyInto(out *ObjectMetadata) {
 	*out = *in
@@ -226,6 +311,22 @@ func (in *ObjectMetadata) DeepCopy() *ObjectMetadata {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectProperties) DeepCopyInto(out *ObjectProperties) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectProperties.
+func (in *ObjectProperties) DeepCopy() *ObjectProperties {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectProperties)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectResource) DeepCopyInto(out *ObjectResource) {
 	*out = *in
@@ -243,10 +344,60 @@ func (in *ObjectResource) DeepCopy() *ObjectResource {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ObjectSelector) DeepCopyInto(out *ObjectSelector) {
+	*out = *in
+	if in.MatchLabels != nil {
+		in, out := &in.MatchLabels, &out.MatchLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MatchExpressions != nil {
+		in, out := &in.MatchExpressions, &out.MatchExpressions
+		*out = make([]metav1.LabelSelectorRequirement, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Owner != nil {
+		in, out := &in.Owner, &out.Owner
+		*out = new(OwnerSelector)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ObjectSelector.
+func (in *ObjectSelector) DeepCopy() *ObjectSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(ObjectSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectTemplate) DeepCopyInto(out *ObjectTemplate) {
 	*out = *in
 	in.ObjectDefinition.DeepCopyInto(&out.ObjectDefinition)
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(Target)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ObjectSelector != nil {
+		in, out := &in.ObjectSelector, &out.ObjectSelector
+		*out = new(ObjectSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Assertions != nil {
+		in, out := &in.Assertions, &out.Assertions
+		*out = make([]Assertion, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -260,6 +411,22 @@ func (in *ObjectTemplate) DeepCopy() *ObjectTemplate {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OwnerSelector) DeepCopyInto(out *OwnerSelector) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OwnerSelector.
+func (in *OwnerSelector) DeepCopy() *OwnerSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(OwnerSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Policy) DeepCopyInto(out *Policy) {
 	*out = *in
@@ -340,6 +507,21 @@ func (in *PolicyRuleTemplate) DeepCopy() *PolicyRuleTemplate {
 func (in *RelatedObject) DeepCopyInto(out *RelatedObject) {
 	*out = *in
 	out.Object = in.Object
+	if in.Differences != nil {
+		in, out := &in.Differences, &out.Differences
+		*out = make([]FieldDifference, len(*in))
+		copy(*out, *in)
+	}
+	if in.FailedAssertions != nil {
+		in, out := &in.FailedAssertions, &out.FailedAssertions
+		*out = make([]AssertionFailure, len(*in))
+		copy(*out, *in)
+	}
+	if in.Properties != nil {
+		in, out := &in.Properties, &out.Properties
+		*out = new(ObjectProperties)
+		**out = **in
+	}
 	return
 }
 
@@ -366,6 +548,20 @@ func (in *Target) DeepCopyInto(out *Target) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.MatchLabels != nil {
+		in, out := &in.MatchLabels, &out.MatchLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MatchExpressions != nil {
+		in, out := &in.MatchExpressions, &out.MatchExpressions
+		*out = make([]metav1.LabelSelectorRequirement, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -390,6 +586,13 @@ func (in *TemplateStatus) DeepCopyInto(out *TemplateStatus) {
 		}
 	}
 	in.Validity.DeepCopyInto(&out.Validity)
+	if in.History != nil {
+		in, out := &in.History, &out.History
+		*out = make([]ComplianceHistory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 	return
 }
 
@@ -0,0 +1,293 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// assertionPathSegment is a field, an index or a wildcard of the path of an assertion
+type assertionPathSegment struct {
+	field    string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// assertionValue is a value selected by the path of an assertion, found is false when the field is missing
+type assertionValue struct {
+	path  string
+	value interface{}
+	found bool
+}
+
+// parseAssertionPath parses the JSONPath of an assertion, such as ".spec.containers[*].image",
+// "{.metadata.labels.app}" or `$.metadata.annotations["example.com/owner"]`
+func parseAssertionPath(path string) ([]assertionPathSegment, error) {
+	trimmed := strings.TrimSpace(path)
+	if strings.HasPrefix(trimmed, "{") && strings.HasSuffix(trimmed, "}") {
+		trimmed = strings.TrimSpace(trimmed[1 : len(trimmed)-1])
+	}
+	trimmed = strings.TrimPrefix(trimmed, "$")
+	segments := []assertionPathSegment{}
+	for i := 0; i < len(trimmed); {
+		switch trimmed[i] {
+		case '.':
+			end := i + 1
+			for end < len(trimmed) && trimmed[end] != '.' && trimmed[end] != '[' {
+				end++
+			}
+			if end == i+1 {
+				return nil, fmt.Errorf("the path %q has an empty field at position %v", path, i)
+			}
+			segments = append(segments, assertionPathSegment{field: trimmed[i+1 : end]})
+			i = end
+		case '[':
+			end := strings.Index(trimmed[i:], "]")
+			if end < 0 {
+				return nil, fmt.Errorf("the path %q has an unclosed bracket at position %v", path, i)
+			}
+			inner := trimmed[i+1 : i+end]
+			switch {
+			case inner == "*":
+				segments = append(segments, assertionPathSegment{wildcard: true})
+			case len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0]:
+				segments = append(segments, assertionPathSegment{field: inner[1 : len(inner)-1]})
+			default:
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("the path %q has an invalid index %q", path, inner)
+				}
+				segments = append(segments, assertionPathSegment{index: index, isIndex: true})
+			}
+			i += end + 1
+		default:
+			return nil, fmt.Errorf("the path %q must start with a . or a [", path)
+		}
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("the path %q selects no field", path)
+	}
+	return segments, nil
+}
+
+// appendAssertionPath returns the path of the field or index of the segment within the parent path
+func appendAssertionPath(parent string, segment assertionPathSegment) string {
+	switch {
+	case segment.wildcard:
+		return parent + "[*]"
+	case segment.isIndex:
+		return fmt.Sprintf("%v[%v]", parent, segment.index)
+	case strings.ContainsAny(segment.field, ".[]"):
+		return fmt.Sprintf("%v[%q]", parent, segment.field)
+	case parent == "":
+		return segment.field
+	}
+	return parent + "." + segment.field
+}
+
+// selectAssertionValues returns the values of the fields selected by the path segments, a missing field is
+// returned as not found with its full path
+func selectAssertionValues(value interface{}, segments []assertionPathSegment, path string) []assertionValue {
+	if len(segments) == 0 {
+		return []assertionValue{{path: path, value: value, found: true}}
+	}
+	segment := segments[0]
+	missing := func() []assertionValue {
+		for _, s := range segments {
+			path = appendAssertionPath(path, s)
+		}
+		return []assertionValue{{path: path}}
+	}
+	switch {
+	case segment.wildcard:
+		values := []assertionValue{}
+		switch typed := value.(type) {
+		case []interface{}:
+			for i, item := range typed {
+				itemPath := appendAssertionPath(path, assertionPathSegment{index: i, isIndex: true})
+				values = append(values, selectAssertionValues(item, segments[1:], itemPath)...)
+			}
+		case map[string]interface{}:
+			keys := make([]string, 0, len(typed))
+			for key := range typed {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				itemPath := appendAssertionPath(path, assertionPathSegment{field: key})
+				values = append(values, selectAssertionValues(typed[key], segments[1:], itemPath)...)
+			}
+		default:
+			return missing()
+		}
+		return values
+	case segment.isIndex:
+		list, ok := value.([]interface{})
+		if !ok || segment.index >= len(list) {
+			return missing()
+		}
+		return selectAssertionValues(list[segment.index], segments[1:], appendAssertionPath(path, segment))
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return missing()
+	}
+	field, found := object[segment.field]
+	if !found {
+		return missing()
+	}
+	return selectAssertionValues(field, segments[1:], appendAssertionPath(path, segment))
+}
+
+// formatAssertionValue returns the string compared with the value of an assertion, the strings are not quoted
+func formatAssertionValue(value interface{}) string {
+	switch typed := value.(type) {
+	case string:
+		return typed
+	case map[string]interface{}, []interface{}:
+		formatted, err := json.Marshal(typed)
+		if err != nil {
+			return fmt.Sprint(typed)
+		}
+		return string(formatted)
+	}
+	return fmt.Sprint(value)
+}
+
+// compareAssertionQuantities compares the actual and expected values as numbers or quantities
+func compareAssertionQuantities(actual string, expected string) (int, error) {
+	actualQuantity, err := resource.ParseQuantity(actual)
+	if err != nil {
+		return 0, fmt.Errorf("the value %v is not a number or a quantity", actual)
+	}
+	expectedQuantity, err := resource.ParseQuantity(expected)
+	if err != nil {
+		return 0, fmt.Errorf("the assertion value %v is not a number or a quantity", expected)
+	}
+	return actualQuantity.Cmp(expectedQuantity), nil
+}
+
+// checkAssertionValue returns whether the selected value satisfies the assertion, and why it could not be compared
+func checkAssertionValue(assertion policyv1.Assertion, selected assertionValue) (bool, error) {
+	operator := strings.ToLower(string(assertion.Operator))
+	switch operator {
+	case strings.ToLower(string(policyv1.AssertExists)):
+		return selected.found && selected.value != nil, nil
+	case strings.ToLower(string(policyv1.AssertDoesNotExist)):
+		return !selected.found || selected.value == nil, nil
+	}
+	if !selected.found {
+		return false, nil
+	}
+	actual := formatAssertionValue(selected.value)
+	switch operator {
+	case strings.ToLower(string(policyv1.AssertEquals)), strings.ToLower(string(policyv1.AssertNotEquals)):
+		equal := actual == assertion.Value
+		if cmp, err := compareAssertionQuantities(actual, assertion.Value); !equal && err == nil {
+			equal = cmp == 0
+		}
+		return equal == (operator == strings.ToLower(string(policyv1.AssertEquals))), nil
+	case strings.ToLower(string(policyv1.AssertGreaterThan)):
+		cmp, err := compareAssertionQuantities(actual, assertion.Value)
+		return err == nil && cmp > 0, err
+	case strings.ToLower(string(policyv1.AssertGreaterThanOrEqual)):
+		cmp, err := compareAssertionQuantities(actual, assertion.Value)
+		return err == nil && cmp >= 0, err
+	case strings.ToLower(string(policyv1.AssertLessThan)):
+		cmp, err := compareAssertionQuantities(actual, assertion.Value)
+		return err == nil && cmp < 0, err
+	case strings.ToLower(string(policyv1.AssertLessThanOrEqual)):
+		cmp, err := compareAssertionQuantities(actual, assertion.Value)
+		return err == nil && cmp <= 0, err
+	case strings.ToLower(string(policyv1.AssertHasPrefix)):
+		return strings.HasPrefix(actual, assertion.Value), nil
+	case strings.ToLower(string(policyv1.AssertHasSuffix)):
+		return strings.HasSuffix(actual, assertion.Value), nil
+	case strings.ToLower(string(policyv1.AssertContains)):
+		return strings.Contains(actual, assertion.Value), nil
+	case strings.ToLower(string(policyv1.AssertMatches)):
+		matched, err := regexp.MatchString(assertion.Value, actual)
+		if err != nil {
+			return false, fmt.Errorf("the assertion value is not a valid regular expression: %v", err)
+		}
+		return matched, nil
+	}
+	return false, fmt.Errorf("the operator %v is not supported", assertion.Operator)
+}
+
+// EvaluateAssertions returns an AssertionFailure for each value of the object failing an assertion, the values of
+// the data of Secrets and the tracked secret values are redacted
+func EvaluateAssertions(assertions []policyv1.Assertion,
+	object map[string]interface{}) []policyv1.AssertionFailure {
+	failures := []policyv1.AssertionFailure{}
+	kind, _ := object["kind"].(string)
+	for _, assertion := range assertions {
+		segments, err := parseAssertionPath(assertion.Path)
+		if err != nil {
+			failures = append(failures, policyv1.AssertionFailure{Path: assertion.Path,
+				Operator: assertion.Operator, Value: assertion.Value, Message: err.Error()})
+			continue
+		}
+		for _, selected := range selectAssertionValues(object, segments, "") {
+			satisfied, err := checkAssertionValue(assertion, selected)
+			if satisfied {
+				continue
+			}
+			failure := policyv1.AssertionFailure{Path: selected.path, Operator: assertion.Operator,
+				Value: assertion.Value}
+			if selected.found {
+				actual, _ := json.Marshal(selected.value)
+				failure.Actual = Redact(string(actual))
+				if kind == "Secret" && !strings.HasPrefix(selected.path, "metadata") {
+					failure.Actual = RedactedValue
+				}
+			}
+			if err != nil {
+				failure.Message = Redact(err.Error())
+			}
+			failures = append(failures, failure)
+		}
+	}
+	return failures
+}
+
+// ValidateAssertion returns an error when the path, the operator or the value of the assertion are not valid
+func ValidateAssertion(assertion policyv1.Assertion) error {
+	if _, err := parseAssertionPath(assertion.Path); err != nil {
+		return err
+	}
+	switch strings.ToLower(string(assertion.Operator)) {
+	case strings.ToLower(string(policyv1.AssertExists)), strings.ToLower(string(policyv1.AssertDoesNotExist)):
+		return nil
+	case strings.ToLower(string(policyv1.AssertEquals)), strings.ToLower(string(policyv1.AssertNotEquals)),
+		strings.ToLower(string(policyv1.AssertHasPrefix)), strings.ToLower(string(policyv1.AssertHasSuffix)),
+		strings.ToLower(string(policyv1.AssertContains)):
+		return nil
+	case strings.ToLower(string(policyv1.AssertGreaterThan)),
+		strings.ToLower(string(policyv1.AssertGreaterThanOrEqual)), strings.ToLower(string(policyv1.AssertLessThan)),
+		strings.ToLower(string(policyv1.AssertLessThanOrEqual)):
+		if _, err := resource.ParseQuantity(assertion.Value); err != nil {
+			return fmt.Errorf("the value %q of the %v operator must be a number or a quantity", assertion.Value,
+				assertion.Operator)
+		}
+		return nil
+	case strings.ToLower(string(policyv1.AssertMatches)):
+		if _, err := regexp.Compile(assertion.Value); err != nil {
+			return fmt.Errorf("the value of the Matches operator is not a valid regular expression: %v", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("the operator `%v` must be one of Equals, NotEquals, GreaterThan, GreaterThanOrEqual, "+
+		"LessThan, LessThanOrEqual, Exists, DoesNotExist, HasPrefix, HasSuffix, Contains or Matches",
+		assertion.Operator)
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package common
+
+import (
+	"testing"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAssertionDeployment(replicas int64, limits ...map[string]interface{}) map[string]interface{} {
+	containers := []interface{}{}
+	for _, limit := range limits {
+		container := map[string]interface{}{"name": "app", "image": "registry.corp.example.com/app:1.0"}
+		if limit != nil {
+			container["resources"] = map[string]interface{}{"limits": limit}
+		}
+		containers = append(containers, container)
+	}
+	return map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":        "app",
+			"annotations": map[string]interface{}{"example.com/owner": "team-a"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": replicas,
+			"template": map[string]interface{}{"spec": map[string]interface{}{"containers": containers}},
+		},
+	}
+}
+
+func TestParseAssertionPath(t *testing.T) {
+	for _, path := range []string{
+		".spec.replicas",
+		"spec.replicas",
+		"{.spec.replicas}",
+		"$.spec.template.spec.containers[*].resources.limits.memory",
+		`.metadata.annotations["example.com/owner"]`,
+		".spec.template.spec.containers[0].image",
+	} {
+		_, err := parseAssertionPath(path)
+		if path == "spec.replicas" {
+			assert.Error(t, err, path)
+		} else {
+			assert.NoError(t, err, path)
+		}
+	}
+	for _, path := range []string{"", "$", ".spec..replicas", ".spec.containers[", ".spec.containers[-1]"} {
+		_, err := parseAssertionPath(path)
+		assert.Error(t, err, path)
+	}
+}
+
+func TestEvaluateAssertions(t *testing.T) {
+	deployment := newAssertionDeployment(1, map[string]interface{}{"memory": "512Mi"},
+		map[string]interface{}{"cpu": "500m"}, nil)
+	failures := EvaluateAssertions([]policyv1.Assertion{
+		{Path: ".spec.replicas", Operator: policyv1.AssertGreaterThanOrEqual, Value: "2"},
+		{Path: ".spec.template.spec.containers[*].resources.limits.memory", Operator: policyv1.AssertExists},
+		{Path: ".spec.template.spec.containers[*].resources.limits.memory", Operator: "lessthanorequal",
+			Value: "1Gi"},
+		{Path: `.metadata.annotations["example.com/owner"]`, Operator: policyv1.AssertEquals, Value: "team-a"},
+		{Path: ".spec.template.spec.containers[0].image", Operator: policyv1.AssertHasPrefix,
+			Value: "registry.corp.example.com/"},
+		{Path: ".spec.paused", Operator: policyv1.AssertDoesNotExist},
+		{Path: ".metadata.name", Operator: policyv1.AssertMatches, Value: "^a[a-z]+$"},
+	}, deployment)
+	assert.Equal(t, []policyv1.AssertionFailure{
+		{Path: "spec.replicas", Operator: policyv1.AssertGreaterThanOrEqual, Value: "2", Actual: "1"},
+		{Path: "spec.template.spec.containers[1].resources.limits.memory", Operator: policyv1.AssertExists},
+		{Path: "spec.template.spec.containers[2].resources.limits.memory", Operator: policyv1.AssertExists},
+		{Path: "spec.template.spec.containers[1].resources.limits.memory", Operator: "lessthanorequal",
+			Value: "1Gi"},
+		{Path: "spec.template.spec.containers[2].resources.limits.memory", Operator: "lessthanorequal",
+			Value: "1Gi"},
+	}, failures)
+
+	assert.Empty(t, EvaluateAssertions([]policyv1.Assertion{
+		{Path: ".spec.replicas", Operator: policyv1.AssertGreaterThan, Value: "2"},
+		{Path: ".spec.replicas", Operator: policyv1.AssertEquals, Value: "3"},
+		{Path: ".spec.template.spec.containers[*].resources.limits.memory", Operator: policyv1.AssertEquals,
+			Value: "1Gi"},
+	}, newAssertionDeployment(3, map[string]interface{}{"memory": "1024Mi"})))
+
+	// the values that are not quantities are reported in the message of the failure
+	failures = EvaluateAssertions([]policyv1.Assertion{
+		{Path: ".metadata.name", Operator: policyv1.AssertLessThan, Value: "2"},
+	}, deployment)
+	assert.Len(t, failures, 1)
+	assert.Equal(t, "the value app is not a number or a quantity", failures[0].Message)
+}
+
+func TestEvaluateAssertionsIngressHosts(t *testing.T) {
+	ingress := map[string]interface{}{
+		"kind": "Ingress",
+		"spec": map[string]interface{}{"rules": []interface{}{
+			map[string]interface{}{"host": "app.corp.example.com"},
+			map[string]interface{}{"host": "app.example.org"},
+		}},
+	}
+	failures := EvaluateAssertions([]policyv1.Assertion{
+		{Path: ".spec.rules[*].host", Operator: policyv1.AssertHasSuffix, Value: ".corp.example.com"},
+	}, ingress)
+	assert.Equal(t, []policyv1.AssertionFailure{{Path: "spec.rules[1].host", Operator: policyv1.AssertHasSuffix,
+		Value: ".corp.example.com", Actual: `"app.example.org"`}}, failures)
+}
+
+func TestEvaluateAssertionsSecret(t *testing.T) {
+	secret := map[string]interface{}{
+		"kind":     "Secret",
+		"metadata": map[string]interface{}{"name": "credentials"},
+		"data":     map[string]interface{}{"password": "c2VjcmV0"},
+	}
+	failures := EvaluateAssertions([]policyv1.Assertion{
+		{Path: ".data.password", Operator: policyv1.AssertEquals, Value: "other"},
+		{Path: ".metadata.name", Operator: policyv1.AssertEquals, Value: "other"},
+	}, secret)
+	assert.Len(t, failures, 2)
+	assert.Equal(t, RedactedValue, failures[0].Actual)
+	assert.Equal(t, `"credentials"`, failures[1].Actual)
+
+	// the secret values copied to other kinds are redacted
+	TrackSecretValue("copiedpassword")
+	failures = EvaluateAssertions([]policyv1.Assertion{
+		{Path: ".data.password", Operator: policyv1.AssertEquals, Value: "other"},
+		{Path: ".data.password", Operator: policyv1.AssertGreaterThan, Value: "2"},
+	}, map[string]interface{}{"kind": "ConfigMap", "data": map[string]interface{}{"password": "copiedpassword"}})
+	assert.Len(t, failures, 2)
+	assert.Equal(t, `"<redacted>"`, failures[0].Actual)
+	assert.Equal(t, "the value <redacted> is not a number or a quantity", failures[1].Message)
+}
+
+func TestValidateAssertion(t *testing.T) {
+	assert.NoError(t, ValidateAssertion(policyv1.Assertion{Path: ".spec.replicas", Operator: "greaterThanOrEqual",
+		Value: "2"}))
+	assert.NoError(t, ValidateAssertion(policyv1.Assertion{Path: ".spec.rules[*].host",
+		Operator: policyv1.AssertMatches, Value: `\.corp\.example\.com$`}))
+	assert.EqualError(t, ValidateAssertion(policyv1.Assertion{Path: ".spec.replicas", Operator: "AtLeast"}),
+		"the operator `AtLeast` must be one of Equals, NotEquals, GreaterThan, GreaterThanOrEqual, LessThan, "+
+			"LessThanOrEqual, Exists, DoesNotExist, HasPrefix, HasSuffix, Contains or Matches")
+	assert.EqualError(t, ValidateAssertion(policyv1.Assertion{Path: ".spec.replicas",
+		Operator: policyv1.AssertGreaterThan, Value: "two"}),
+		`the value "two" of the GreaterThan operator must be a number or a quantity`)
+	assert.Error(t, ValidateAssertion(policyv1.Assertion{Path: ".metadata.name", Operator: policyv1.AssertMatches,
+		Value: "("}))
+	assert.Error(t, ValidateAssertion(policyv1.Assertion{Path: "spec", Operator: policyv1.AssertExists}))
+}
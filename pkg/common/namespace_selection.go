@@ -6,7 +6,10 @@ package common
 import (
 	"context"
 
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
 )
 
 //=================================================================
@@ -36,12 +39,81 @@ func GetSelectedNamespaces(included, excluded, allNamespaces []string) []string
 //=================================================================
 //GetAllNamespaces gets the list of all namespaces from k8s
 func GetAllNamespaces() (list []string, err error) {
+	return GetLabeledNamespaces(nil)
+}
+
+//=================================================================
+// GetLabeledNamespaces gets the list of namespaces matching the label selector from k8s,
+// all namespaces are returned when the selector is nil
+func GetLabeledNamespaces(selector labels.Selector) (list []string, err error) {
+	listOpts := metav1.ListOptions{}
+	if selector != nil {
+		listOpts.LabelSelector = selector.String()
+	}
 	namespaces := (*KubeClient).CoreV1().Namespaces()
-	namespaceList, err := namespaces.List(context.TODO(), metav1.ListOptions{})
+	namespaceList, err := namespaces.List(context.TODO(), listOpts)
 
 	namespacesNames := []string{}
+	if namespaceList == nil {
+		return namespacesNames, err
+	}
 	for _, n := range namespaceList.Items {
 		namespacesNames = append(namespacesNames, n.Name)
 	}
 	return namespacesNames, err
 }
+
+//=================================================================
+// NamespaceLabelSelector builds the label selector used to pick namespaces from the matchLabels and
+// matchExpressions of the namespace selector and the policy labelSelector. It returns nil if none are set.
+func NamespaceLabelSelector(target policyv1.Target, labelSelector map[string]string) (labels.Selector, error) {
+	if len(target.MatchLabels) == 0 && len(target.MatchExpressions) == 0 && len(labelSelector) == 0 {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+		MatchLabels:      target.MatchLabels,
+		MatchExpressions: target.MatchExpressions,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// the policy labelSelector is added as requirements so it is ANDed even if a key is repeated
+	for key, value := range labelSelector {
+		req, err := labels.NewRequirement(key, selection.Equals, []string{value})
+		if err != nil {
+			return nil, err
+		}
+		selector = selector.Add(*req)
+	}
+	return selector, nil
+}
+
+//=================================================================
+// SelectNamespaces returns the namespaces picked by the namespace selector and the policy labelSelector.
+// Only namespaces matching the labels are considered for the include/exclude patterns, and when labels
+// are set without any include pattern, all the labeled namespaces are included.
+func SelectNamespaces(target policyv1.Target, labelSelector map[string]string) ([]string, error) {
+	return SelectNamespacesWith(GetLabeledNamespaces, target, labelSelector)
+}
+
+// NamespaceLister lists the namespaces matching the label selector, all namespaces when the selector is nil
+type NamespaceLister func(selector labels.Selector) ([]string, error)
+
+//=================================================================
+// SelectNamespacesWith is SelectNamespaces with the namespaces listed by the given lister
+func SelectNamespacesWith(lister NamespaceLister, target policyv1.Target,
+	labelSelector map[string]string) ([]string, error) {
+	selector, err := NamespaceLabelSelector(target, labelSelector)
+	if err != nil {
+		return []string{}, err
+	}
+	candidates, err := lister(selector)
+	if err != nil {
+		return []string{}, err
+	}
+	included := target.Include
+	if selector != nil && len(included) == 0 {
+		included = []string{"*"}
+	}
+	return GetSelectedNamespaces(included, target.Exclude, candidates), nil
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/onsi/gomega"
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
 	"github.com/stretchr/testify/assert"
 	coretypes "k8s.io/api/core/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -99,3 +100,82 @@ func TestGetAllNamespaces(t *testing.T) {
 	_, err := GetAllNamespaces()
 	assert.Nil(t, err)
 }
+
+func TestSelectNamespaces(t *testing.T) {
+	var simpleClient kubernetes.Interface = testclient.NewSimpleClientset()
+	nsLabels := map[string]map[string]string{
+		"default":        {},
+		"tenant-a8f3k":   {"tenant": "payments", "env": "prod"},
+		"tenant-q2x9z":   {"tenant": "payments", "env": "dev"},
+		"tenant-c7m1p":   {"tenant": "billing", "env": "prod"},
+		"kube-system":    {"env": "prod"},
+		"payments-infra": {"tenant": "payments"},
+	}
+	for name, nsLabels := range nsLabels {
+		ns := coretypes.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: nsLabels}}
+		simpleClient.CoreV1().Namespaces().Create(context.TODO(), &ns, metav1.CreateOptions{})
+	}
+	Initialize(&simpleClient, nil)
+
+	tests := []struct {
+		target        policyv1.Target
+		labelSelector map[string]string
+		expected      []string
+	}{
+		{
+			policyv1.Target{Include: []string{"default", "kube-*"}},
+			nil,
+			[]string{"default", "kube-system"},
+		},
+		{
+			policyv1.Target{MatchLabels: map[string]string{"tenant": "payments"}},
+			nil,
+			[]string{"payments-infra", "tenant-a8f3k", "tenant-q2x9z"},
+		},
+		{
+			policyv1.Target{
+				Include:     []string{"tenant-*"},
+				MatchLabels: map[string]string{"tenant": "payments"},
+			},
+			nil,
+			[]string{"tenant-a8f3k", "tenant-q2x9z"},
+		},
+		{
+			policyv1.Target{
+				Exclude: []string{"kube-*"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "env", Operator: metav1.LabelSelectorOpIn, Values: []string{"prod"}},
+				},
+			},
+			nil,
+			[]string{"tenant-a8f3k", "tenant-c7m1p"},
+		},
+		{
+			policyv1.Target{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{Key: "tenant", Operator: metav1.LabelSelectorOpExists},
+				},
+			},
+			map[string]string{"env": "prod"},
+			[]string{"tenant-a8f3k", "tenant-c7m1p"},
+		},
+		{
+			policyv1.Target{},
+			map[string]string{"tenant": "billing"},
+			[]string{"tenant-c7m1p"},
+		},
+	}
+	for _, test := range tests {
+		actual, err := SelectNamespaces(test.target, test.labelSelector)
+		assert.Nil(t, err)
+		sort.Strings(actual)
+		assert.Equal(t, test.expected, actual)
+	}
+
+	_, err := SelectNamespaces(policyv1.Target{
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "env", Operator: "Matches", Values: []string{"prod"}},
+		},
+	}, nil)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package common
+
+import (
+	"errors"
+	"fmt"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ObjectSelectors parses the label and field selectors of the objectSelector of an object template, they are nil
+// when unset. An error is also returned when the owner has no kind.
+func ObjectSelectors(selector policyv1.ObjectSelector) (labels.Selector, fields.Selector, error) {
+	var labelSelector labels.Selector
+	if len(selector.MatchLabels) > 0 || len(selector.MatchExpressions) > 0 {
+		var err error
+		labelSelector, err = metav1.LabelSelectorAsSelector(&metav1.LabelSelector{
+			MatchLabels:      selector.MatchLabels,
+			MatchExpressions: selector.MatchExpressions,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("the label selector is not valid: %v", err)
+		}
+	}
+	var fieldSelector fields.Selector
+	if selector.FieldSelector != "" {
+		var err error
+		fieldSelector, err = fields.ParseSelector(selector.FieldSelector)
+		if err != nil {
+			return nil, nil, fmt.Errorf("the field selector is not valid: %v", err)
+		}
+	}
+	if selector.Owner != nil && selector.Owner.Kind == "" {
+		return nil, nil, errors.New("the owner must have a kind")
+	}
+	return labelSelector, fieldSelector, nil
+}
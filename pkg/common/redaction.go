@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package common
+
+import (
+	base64 "encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RedactedValue replaces the secret values in the logs, the events and the status of the policies
+const RedactedValue = "<redacted>"
+
+// minSecretValueLength is the length of the shortest secret value redacted, the shorter values like "true" or
+// "1" would redact unrelated text
+const minSecretValueLength = 4
+
+// secretValues holds the values read from Secrets or set in the object definitions of Secrets since the last
+// call to ExpireSecretValues, and previousSecretValues the values tracked before it. The replacer redacting them
+// is created again when a value is added or expired.
+var secretValues = map[string]bool{}
+var previousSecretValues = map[string]bool{}
+var secretReplacer *strings.Replacer
+var secretValuesMx sync.RWMutex
+
+// TrackSecretValue redacts the value, and its base64 encoding, from the text passed to Redact
+func TrackSecretValue(value string) {
+	values := []string{value, base64.StdEncoding.EncodeToString([]byte(value))}
+	secretValuesMx.Lock()
+	defer secretValuesMx.Unlock()
+	for _, v := range values {
+		if len(v) >= minSecretValueLength && !secretValues[v] {
+			secretValues[v] = true
+			if !previousSecretValues[v] {
+				secretReplacer = nil
+			}
+		}
+	}
+}
+
+// ExpireSecretValues stops redacting the values that were not tracked again since the previous call, so that the
+// values of the changed and deleted Secrets are not kept forever. It is called before an evaluation of every
+// policy, which tracks the values they read again, since the values read by a policy are redacted from the others.
+func ExpireSecretValues() {
+	secretValuesMx.Lock()
+	defer secretValuesMx.Unlock()
+	for value := range previousSecretValues {
+		if !secretValues[value] {
+			secretReplacer = nil
+			break
+		}
+	}
+	previousSecretValues = secretValues
+	secretValues = map[string]bool{}
+}
+
+// TrackSecretObject redacts the values of the data and stringData of an object when it is a Secret, or of the
+// Secrets of a list, from the text passed to Redact. The values with templates are tracked once resolved.
+func TrackSecretObject(object map[string]interface{}) {
+	if items, ok := object["items"].([]interface{}); ok {
+		for _, item := range items {
+			if itemObject, ok := item.(map[string]interface{}); ok {
+				TrackSecretObject(itemObject)
+			}
+		}
+	}
+	if kind, _ := object["kind"].(string); kind != "Secret" {
+		return
+	}
+	if data, ok := object["data"].(map[string]interface{}); ok {
+		for _, value := range data {
+			encoded := fmt.Sprint(value)
+			if strings.Contains(encoded, "{{") {
+				continue
+			}
+			TrackSecretValue(encoded)
+			if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+				TrackSecretValue(string(decoded))
+			}
+		}
+	}
+	if stringData, ok := object["stringData"].(map[string]interface{}); ok {
+		for _, value := range stringData {
+			if decoded := fmt.Sprint(value); !strings.Contains(decoded, "{{") {
+				TrackSecretValue(decoded)
+			}
+		}
+	}
+}
+
+// Redact replaces the tracked secret values in the text with RedactedValue
+func Redact(text string) string {
+	secretValuesMx.RLock()
+	replacer := secretReplacer
+	empty := len(secretValues) == 0 && len(previousSecretValues) == 0
+	secretValuesMx.RUnlock()
+	if empty {
+		return text
+	}
+	if replacer == nil {
+		secretValuesMx.Lock()
+		if secretReplacer == nil {
+			// the longest values are replaced first, so that a value containing another one is fully redacted
+			values := make([]string, 0, len(secretValues)+len(previousSecretValues))
+			for value := range secretValues {
+				values = append(values, value)
+			}
+			for value := range previousSecretValues {
+				if !secretValues[value] {
+					values = append(values, value)
+				}
+			}
+			sort.Slice(values, func(i, j int) bool {
+				if len(values[i]) != len(values[j]) {
+					return len(values[i]) > len(values[j])
+				}
+				return values[i] < values[j]
+			})
+			oldnew := make([]string, 0, 2*len(values))
+			for _, value := range values {
+				oldnew = append(oldnew, value, RedactedValue)
+			}
+			secretReplacer = strings.NewReplacer(oldnew...)
+		}
+		replacer = secretReplacer
+		secretValuesMx.Unlock()
+	}
+	return replacer.Replace(text)
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedact(t *testing.T) {
+	assert.Equal(t, "nothing tracked: password", Redact("nothing tracked: password"))
+
+	TrackSecretValue("s3cr3tpassword")
+	// the short values are not tracked
+	TrackSecretValue("abc")
+	assert.Equal(t, "value <redacted> and <redacted>, abc",
+		Redact("value s3cr3tpassword and czNjcjN0cGFzc3dvcmQ=, abc"))
+
+	// the longest value is redacted first
+	TrackSecretValue("s3cr3t")
+	assert.Equal(t, "<redacted> <redacted>", Redact("s3cr3tpassword s3cr3t"))
+}
+
+func TestTrackSecretObject(t *testing.T) {
+	TrackSecretObject(map[string]interface{}{
+		"kind":       "ConfigMap",
+		"data":       map[string]interface{}{"key": "configvalue"},
+		"stringData": map[string]interface{}{"key": "configstring"},
+	})
+	TrackSecretObject(map[string]interface{}{
+		"kind": "SecretList",
+		"items": []interface{}{map[string]interface{}{
+			"kind": "Secret",
+			// the base64 encoding of secretdata
+			"data":       map[string]interface{}{"key": "c2VjcmV0ZGF0YQ==", "templated": "{{ fromSecret }}"},
+			"stringData": map[string]interface{}{"key": "secretstring"},
+		}},
+	})
+
+	assert.Equal(t, "configvalue configstring {{ fromSecret }}",
+		Redact("configvalue configstring {{ fromSecret }}"))
+	assert.Equal(t, "<redacted> <redacted> <redacted>", Redact("secretdata c2VjcmV0ZGF0YQ== secretstring"))
+}
+
+func TestExpireSecretValues(t *testing.T) {
+	TrackSecretValue("rotatedpassword")
+	TrackSecretValue("currentpassword")
+	ExpireSecretValues()
+	// the values tracked before the previous call are still redacted
+	assert.Equal(t, "<redacted> <redacted>", Redact("rotatedpassword currentpassword"))
+
+	TrackSecretValue("currentpassword")
+	ExpireSecretValues()
+	assert.Equal(t, "rotatedpassword <redacted>", Redact("rotatedpassword currentpassword"))
+}
@@ -51,3 +51,14 @@ func (spm *SyncedPolicyMap) RemoveObject(key string) {
 	}
 	delete(spm.PolicyMap, key)
 }
+
+// GetAll returns a copy of the map, safe to iterate while the map is modified
+func (spm *SyncedPolicyMap) GetAll() map[string]*policiesv1.ConfigurationPolicy {
+	spm.Mx.RLock()
+	defer spm.Mx.RUnlock()
+	all := make(map[string]*policiesv1.ConfigurationPolicy, len(spm.PolicyMap))
+	for key, plc := range spm.PolicyMap {
+		all[key] = plc
+	}
+	return all
+}
@@ -89,3 +89,19 @@ func TestRemoveDataObject(t *testing.T) {
 		t.Fatalf("expecting found = false, however found = %v", found)
 	}
 }
+
+func TestGetAll(t *testing.T) {
+	sm.AddObject("default", plc)
+	all := sm.GetAll()
+	if len(all) != 1 || all["default"] != plc {
+		t.Fatalf("expecting a copy with the default policy, however got %v", all)
+	}
+	//the copy is not affected by later changes to the map
+	sm.RemoveObject("default")
+	if _, found := all["default"]; !found {
+		t.Fatalf("expecting the copy to keep the default policy")
+	}
+	if len(sm.GetAll()) != 0 {
+		t.Fatalf("expecting an empty copy, however got %v", sm.GetAll())
+	}
+}
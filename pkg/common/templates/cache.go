@@ -0,0 +1,92 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package templates
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	cacheHitsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "config_policy_template_cache_hits_total",
+			Help: "The number of template lookups answered by the cache of the evaluation cycle",
+		},
+		[]string{"function"},
+	)
+	cacheMissesCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "config_policy_template_cache_misses_total",
+			Help: "The number of template lookups sent to the API server",
+		},
+		[]string{"function"},
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(cacheHitsCounter, cacheMissesCounter)
+}
+
+// cachedResult is the result of a template lookup, a not found error is cached like a value
+type cachedResult struct {
+	value interface{}
+	err   error
+}
+
+// lookupCache holds the results of the template lookups while it is enabled, so that the templates of the
+// policies evaluated in a cycle read each object once. It is nil when the cache is disabled.
+var lookupCache map[string]cachedResult
+var lookupCacheMx sync.Mutex
+
+// EnableCache caches the results of the template lookups until DisableCache is called, the controller enables
+// it for each evaluation cycle. The results are not cached when it is not enabled.
+func EnableCache() {
+	lookupCacheMx.Lock()
+	defer lookupCacheMx.Unlock()
+	lookupCache = map[string]cachedResult{}
+}
+
+// DisableCache drops the cached results of the template lookups and stops caching them
+func DisableCache() {
+	lookupCacheMx.Lock()
+	defer lookupCacheMx.Unlock()
+	lookupCache = nil
+}
+
+// cachedGet returns the cached result of a lookup of function with args read with the client, or calls get and
+// caches its result when it succeeds or the object is not found. The results of different clients, which may read
+// different clusters, are cached apart. The cached values are shared and must not be modified.
+func cachedGet(client interface{}, function string, args []string,
+	get func() (interface{}, error)) (interface{}, error) {
+	key := fmt.Sprintf("%p/%v/%v", client, function, strings.Join(args, "/"))
+
+	lookupCacheMx.Lock()
+	enabled := lookupCache != nil
+	result, found := lookupCache[key]
+	lookupCacheMx.Unlock()
+	if found {
+		glog.V(2).Infof("template cache hit for %v", key)
+		cacheHitsCounter.WithLabelValues(function).Inc()
+		return result.value, result.err
+	}
+	cacheMissesCounter.WithLabelValues(function).Inc()
+
+	value, err := get()
+	if enabled && (err == nil || apierrors.IsNotFound(err)) {
+		lookupCacheMx.Lock()
+		// the cache may have been disabled in the meantime
+		if lookupCache != nil {
+			lookupCache[key] = cachedResult{value: value, err: err}
+		}
+		lookupCacheMx.Unlock()
+	}
+	return value, err
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package templates
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCachedLookups(t *testing.T) {
+	configmaps := testClients.Kube.CoreV1().ConfigMaps("testns")
+	configmap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "cachedconfigmap"},
+		Data:       map[string]string{"key": "value"},
+	}
+	if _, err := configmaps.Create(context.TODO(), configmap, metav1.CreateOptions{}); err != nil {
+		t.Fatalf(err.Error())
+	}
+	defer configmaps.Delete(context.TODO(), "cachedconfigmap", metav1.DeleteOptions{})
+	hits := testutil.ToFloat64(cacheHitsCounter.WithLabelValues("fromConfigMap"))
+	misses := testutil.ToFloat64(cacheMissesCounter.WithLabelValues("fromConfigMap"))
+
+	EnableCache()
+	testcases := []struct {
+		expectedResult string
+		expectedHits   float64
+		expectedMisses float64
+	}{
+		{"value", hits, misses + 1},
+		// the configmap is updated below, the cached value is returned until the cache is disabled
+		{"value", hits + 1, misses + 1},
+	}
+	for _, test := range testcases {
+		val, err := fromConfigMap(testClients, "testns", "cachedconfigmap", "key")
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if val != test.expectedResult {
+			t.Fatalf("expected : %s , got : %s", test.expectedResult, val)
+		}
+		if testutil.ToFloat64(cacheHitsCounter.WithLabelValues("fromConfigMap")) != test.expectedHits ||
+			testutil.ToFloat64(cacheMissesCounter.WithLabelValues("fromConfigMap")) != test.expectedMisses {
+			t.Fatalf("expected %v hits and %v misses", test.expectedHits, test.expectedMisses)
+		}
+		configmap.Data["key"] = "updated"
+		if _, err := configmaps.Update(context.TODO(), configmap, metav1.UpdateOptions{}); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+
+	// a missing configmap is cached like a value
+	for i := 0; i < 2; i++ {
+		if _, err := fromConfigMap(testClients, "testns", "idontexist", "key"); err == nil {
+			t.Fatalf("expected a not found error")
+		}
+	}
+	if testutil.ToFloat64(cacheHitsCounter.WithLabelValues("fromConfigMap")) != hits+2 {
+		t.Fatalf("expected the not found error to be cached")
+	}
+
+	DisableCache()
+	val, _ := fromConfigMap(testClients, "testns", "cachedconfigmap", "key")
+	if val != "updated" {
+		t.Fatalf("expected : updated , got : %s", val)
+	}
+	if testutil.ToFloat64(cacheHitsCounter.WithLabelValues("fromConfigMap")) != hits+2 {
+		t.Fatalf("expected no cache hit when the cache is disabled")
+	}
+}
@@ -7,13 +7,15 @@ import (
 	"context"
 	"github.com/golang/glog"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 )
 
 // retrieve Spec value for the given clusterclaim
-func fromClusterClaim(claimname string) (string, error) {
+func fromClusterClaim(clients Clients, claimname string) (string, error) {
 	result := map[string]interface{}{}
 
 	dclient, dclientErr := getDynamicClient(
+		clients,
 		"cluster.open-cluster-management.io/v1alpha1",
 		"ClusterClaim",
 		"",
@@ -22,13 +24,16 @@ func fromClusterClaim(claimname string) (string, error) {
 		return "", dclientErr
 	}
 
-	getObj, getErr := dclient.Get(context.TODO(), claimname, metav1.GetOptions{})
+	getObj, getErr := cachedGet(clients.Dynamic, "fromClusterClaim", []string{claimname}, func() (interface{},
+		error) {
+		return dclient.Get(context.TODO(), claimname, metav1.GetOptions{})
+	})
 	if getErr != nil {
 		glog.Errorf("Error retrieving clusterclaim : %v, %v", claimname, getErr)
 		return "", getErr
 	}
 
-	result = getObj.UnstructuredContent()
+	result = getObj.(*unstructured.Unstructured).UnstructuredContent()
 
 	spec := result["spec"].(map[string]interface{})
 	if _, ok := spec["value"]; ok {
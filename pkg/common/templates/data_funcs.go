@@ -0,0 +1,127 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package templates
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// the string and data helpers of the templates, they are deterministic so that a template resolves to the same
+// object on every evaluation. The argument the helper works on is the last one so that they can be piped, as in
+// {{ "a,b" | split "," | join ";" }}
+
+func trimPrefix(prefix string, s string) string {
+	return strings.TrimPrefix(s, prefix)
+}
+
+func trimSuffix(suffix string, s string) string {
+	return strings.TrimSuffix(s, suffix)
+}
+
+func replace(old string, new string, s string) string {
+	return strings.ReplaceAll(s, old, new)
+}
+
+func split(sep string, s string) []string {
+	return strings.Split(s, sep)
+}
+
+// join joins the items of a list of any type with sep, the items are formatted with fmt
+func join(sep string, list interface{}) (string, error) {
+	value := reflect.ValueOf(list)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return "", fmt.Errorf("expected a list, got %T", list)
+	}
+	items := make([]string, value.Len())
+	for i := range items {
+		items[i] = fmt.Sprint(value.Index(i).Interface())
+	}
+	return strings.Join(items, sep), nil
+}
+
+func contains(substr string, s string) bool {
+	return strings.Contains(s, substr)
+}
+
+func hasPrefix(prefix string, s string) bool {
+	return strings.HasPrefix(s, prefix)
+}
+
+func hasSuffix(suffix string, s string) bool {
+	return strings.HasSuffix(s, suffix)
+}
+
+// defaultValue returns the value, or def when the value is nil or the zero value of its type, or an empty list
+// or map
+func defaultValue(def interface{}, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map, reflect.String:
+		if v.Len() == 0 {
+			return def
+		}
+	default:
+		if v.IsZero() {
+			return def
+		}
+	}
+	return value
+}
+
+func regexMatch(regex string, s string) (bool, error) {
+	return regexp.MatchString(regex, s)
+}
+
+// regexReplaceAll replaces the matches of the regex in s with repl, where $1 is the first submatch
+func regexReplaceAll(regex string, repl string, s string) (string, error) {
+	re, err := regexp.Compile(regex)
+	if err != nil {
+		return "", err
+	}
+	return re.ReplaceAllString(s, repl), nil
+}
+
+// sha256sum returns the hex encoded SHA-256 hash of s
+func sha256sum(s string) string {
+	hash := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(hash[:])
+}
+
+// dict returns a map of the key and value pairs, as in {{ dict "key1" "value1" "key2" "value2" }}
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("expected key and value pairs, got %v arguments", len(pairs))
+	}
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string key, got %T", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+func list(items ...interface{}) []interface{} {
+	return items
+}
+
+// toJSON converts a value to a compact JSON document, the keys of the maps are sorted
+func toJSON(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package templates
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDataFuncs(t *testing.T) {
+	testcases := []struct {
+		inputTmpl      string
+		expectedResult string
+		expectedErr    error
+	}{
+		{`value: '{{ "  text " | trim }}'`, "value: text", nil},
+		{`value: '{{ "v1.2.3" | trimPrefix "v" | trimSuffix ".3" }}'`, `value: "1.2"`, nil},
+		{`value: '{{ "a-b-c" | replace "-" "_" }}'`, "value: a_b_c", nil},
+		{`value: '{{ "a,b,c" | split "," | join ";" }}'`, "value: a;b;c", nil},
+		{`value: '{{ list 1 "b" true | join "," }}'`, "value: 1,b,true", nil},
+		{`value: '{{ "Hello" | lower }}-{{ "world" | upper }}'`, "value: hello-WORLD", nil},
+		{`value: '{{ if "cmkey1" | hasPrefix "cm" }}prefix{{ end }}'`, "value: prefix", nil},
+		{`value: '{{ printf "%s-%d" "app" 1 }}'`, "value: app-1", nil},
+		{`value: '{{ "" | default "fallback" }}'`, "value: fallback", nil},
+		{`value: '{{ fromConfigMap "testns" "testconfigmap" "cmkey1" | default "fallback" }}'`,
+			"value: cmkey1Val", nil},
+		{`value: '{{ fromConfigMap "testns" "testconfigmap" "idontexist" | default "fallback" }}'`,
+			"value: fallback", nil},
+		{`value: '{{ regexMatch "^cm[a-z]+[0-9]$" "cmkey1" }}'`, `value: "true"`, nil},
+		{`value: '{{ regexReplaceAll "([a-z]+)-([0-9]+)" "${2}_${1}" "app-1" }}'`, "value: 1_app", nil},
+		{`value: '{{ "app-1" | regexReplaceAll "-([0-9]+)" "_$1" }}'`, "value: app_1", nil},
+		{`value: '{{ "test" | sha256sum }}'`,
+			"value: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", nil},
+		{`value: '{{ dict "b" 1 "a" (list "x" "y") | toJSON }}'`, `value: '{"a":["x","y"],"b":1}'`, nil},
+		{`value: '{{ dict "key" "value" | toYAML }}'`, `value: 'key: value'`, nil},
+		{`value: '{{ dict "key" }}'`, "",
+			errors.New(`template error in function dict at {{ dict "key" }}: ` +
+				"expected key and value pairs, got 1 arguments")},
+		{`value: '{{ regexMatch "(" "text" }}'`, "",
+			errors.New(`template error in function regexMatch at {{ regexMatch "(" "text" }}: ` +
+				"error parsing regexp: missing closing ): `(`")},
+	}
+
+	for _, test := range testcases {
+		tmplMap, _ := fromYAML(test.inputTmpl)
+		val, err := ResolveTemplate(tmplMap, testClients)
+
+		if err != nil {
+			if test.expectedErr == nil {
+				t.Fatalf(err.Error())
+			}
+			if !strings.EqualFold(test.expectedErr.Error(), err.Error()) {
+				t.Fatalf("expected err: %s got err: %s", test.expectedErr, err)
+			}
+		} else {
+			if test.expectedErr != nil {
+				t.Fatalf("expected err: %s got none", test.expectedErr)
+			}
+			val, _ := toYAML(val)
+			if val != test.expectedResult {
+				t.Fatalf("expected : %s , got : %s", test.expectedResult, val)
+			}
+		}
+	}
+}
+
+func TestDefaultValue(t *testing.T) {
+	testcases := []struct {
+		input          interface{}
+		expectedResult interface{}
+	}{
+		{nil, "default"},
+		{"", "default"},
+		{0, "default"},
+		{false, "default"},
+		{[]interface{}{}, "default"},
+		{map[string]interface{}{}, "default"},
+		{"value", "value"},
+		{1, 1},
+		{true, true},
+	}
+
+	for _, test := range testcases {
+		val := defaultValue("default", test.input)
+		if val != test.expectedResult {
+			t.Fatalf("expected : %v , got : %v", test.expectedResult, val)
+		}
+	}
+}
@@ -5,41 +5,49 @@ package templates
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"github.com/golang/glog"
+	"github.com/open-cluster-management/config-policy-controller/pkg/common"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
-	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/dynamic"
 )
 
-func lookup(apiversion string, kind string, namespace string, rsrcname string) (map[string]interface{}, error) {
+func lookup(clients Clients, apiversion string, kind string, namespace string,
+	rsrcname string) (map[string]interface{}, error) {
 	glog.V(2).Infof("lookup :  %v, %v, %v, %v", apiversion, kind, namespace, rsrcname)
 
 	result := map[string]interface{}{}
 
 	//get dynamic Client for the given GVK and namespace
-	dclient, dclientErr := getDynamicClient(apiversion, kind, namespace)
+	dclient, dclientErr := getDynamicClient(clients, apiversion, kind, namespace)
 	if dclientErr != nil {
 		return result, dclientErr
 	}
 
 	//if resourcename is  set then get the specific resource
 	//else get list of all resources for that (gvk, ns)
-
-	var lookupErr error
-	if rsrcname != "" {
-		getObj, getErr := dclient.Get(context.TODO(), rsrcname, metav1.GetOptions{})
-		if getErr == nil {
-			result = getObj.UnstructuredContent()
-		}
-		lookupErr = getErr
-	} else {
-		listObj, listErr := dclient.List(context.TODO(), metav1.ListOptions{})
-		if listErr == nil {
-			result = listObj.UnstructuredContent()
-		}
-		lookupErr = listErr
+	//the results are cached for the evaluation cycle
+
+	lookupObj, lookupErr := cachedGet(clients.Dynamic, "lookup", []string{apiversion, kind, namespace, rsrcname},
+		func() (interface{}, error) {
+			if rsrcname != "" {
+				getObj, getErr := dclient.Get(context.TODO(), rsrcname, metav1.GetOptions{})
+				if getErr != nil {
+					return nil, getErr
+				}
+				return getObj.UnstructuredContent(), nil
+			}
+			listObj, listErr := dclient.List(context.TODO(), metav1.ListOptions{})
+			if listErr != nil {
+				return nil, listErr
+			}
+			return listObj.UnstructuredContent(), nil
+		})
+	if lookupErr == nil {
+		result = lookupObj.(map[string]interface{})
 	}
 
 	if lookupErr != nil {
@@ -48,12 +56,15 @@ func lookup(apiversion string, kind string, namespace string, rsrcname string) (
 		}
 	}
 
-	glog.V(2).Infof("lookup result:  %v", result)
+	// the values of the Secrets looked up are redacted
+	common.TrackSecretObject(result)
+	glog.V(2).Infof("lookup result:  %v", common.Redact(fmt.Sprint(result)))
 	return result, lookupErr
 }
 
 //this func finds the GVR for given GVK and returns a namespaced dynamic client
-func getDynamicClient(apiversion string, kind string, namespace string) (dynamic.ResourceInterface, error) {
+func getDynamicClient(clients Clients, apiversion string, kind string,
+	namespace string) (dynamic.ResourceInterface, error) {
 
 	var dclient dynamic.ResourceInterface
 	gvk := schema.FromAPIVersionAndKind(apiversion, kind)
@@ -61,7 +72,7 @@ func getDynamicClient(apiversion string, kind string, namespace string) (dynamic
 
 	// we have GVK but We need GVR i.e resourcename for kind inorder to create dynamicClient
 	// find ApiResource for given GVK
-	apiResource, findErr := findAPIResource(gvk)
+	apiResource, findErr := findAPIResource(clients, gvk)
 	if findErr != nil {
 		return nil, findErr
 	}
@@ -73,10 +84,9 @@ func getDynamicClient(apiversion string, kind string, namespace string) (dynamic
 	}
 	glog.V(2).Infof("GVR is:  %v", gvr)
 
-	//get Dynamic Client
-	dclientIntf, dclientErr := dynamic.NewForConfig(kubeConfig)
+	//get the Dynamic Client of the templates
+	dclientIntf, dclientErr := clients.getDynamicInterface()
 	if dclientErr != nil {
-		glog.Errorf("Failed to get dynamic client with err: %v", dclientErr)
 		return nil, dclientErr
 	}
 
@@ -94,20 +104,15 @@ func getDynamicClient(apiversion string, kind string, namespace string) (dynamic
 	return dclient, nil
 }
 
-func findAPIResource(gvk schema.GroupVersionKind) (metav1.APIResource, error) {
+func findAPIResource(clients Clients, gvk schema.GroupVersionKind) (metav1.APIResource, error) {
 	glog.V(2).Infof("GVK is:  %v", gvk)
 
 	apiResource := metav1.APIResource{}
 
-	//check if an apiresource list is available already (i.e provided as input to templates)
-	//if not available use api discovery client to get api resource list
-	apiResList := kubeAPIResourceList
+	//the apiresource list is discovered by the caller resolving the templates
+	apiResList := clients.APIResources
 	if apiResList == nil {
-		var ddErr error
-		apiResList, ddErr = discoverAPIResources()
-		if ddErr != nil {
-			return apiResource, ddErr
-		}
+		return apiResource, errors.New("the API resources were not set to resolve the templates")
 	}
 
 	//find apiResourcefor given GVK
@@ -132,27 +137,11 @@ func findAPIResource(gvk schema.GroupVersionKind) (metav1.APIResource, error) {
 		}
 	}
 
-	glog.V(2).Infof("found APIResource :  %v", apiResource)
-	return apiResource, nil
-}
-
-// Configpolicycontroller sets the apiresource list on the template processor
-// So this func shouldnt  execute in the configpolicy flow
-// including this just for completeness
-func discoverAPIResources() ([]*metav1.APIResourceList, error) {
-	glog.V(2).Infof("discover APIResources")
-
-	dd, ddErr := discovery.NewDiscoveryClientForConfig(kubeConfig)
-	if ddErr != nil {
-		glog.Errorf("Failed to create discovery client with err: %v", ddErr)
-		return nil, ddErr
-	}
-	apiresourcelist, apiresourcelistErr := dd.ServerResources()
-	if apiresourcelistErr != nil {
-		glog.Errorf("Failed to retrieve apiresourcelist with err: %v", apiresourcelistErr)
-		return nil, apiresourcelistErr
+	// an undiscovered kind has no scope, it can't be read without knowing whether it is namespaced
+	if apiResource.Name == "" {
+		return apiResource, fmt.Errorf("the kind %v of %v was not found on the cluster", gvk.Kind, groupVersion)
 	}
 
-	glog.V(2).Infof("discovered APIResources :  %v", apiresourcelist)
-	return apiresourcelist, nil
+	glog.V(2).Infof("found APIResource :  %v", apiResource)
+	return apiResource, nil
 }
@@ -0,0 +1,125 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package templates
+
+import (
+	"context"
+	base64 "encoding/base64"
+	"errors"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/open-cluster-management/config-policy-controller/pkg/common"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// the hub functions read from the namespace of the managed cluster on the hub, the client is created on the first
+// read since the hub kubeconfig secret may be created after the controller starts, and created again after the hub
+// rejects its credentials since the hub kubeconfig secret may have been rotated
+var hubClient kubernetes.Interface
+var hubConfigLoader func() (*rest.Config, error)
+var hubNamespace string
+var hubClientMx sync.Mutex
+
+// InitializeHubClient enables the hub template functions, they read from the namespace of the managed cluster on
+// the hub with the config returned by loadHubConfig
+func InitializeHubClient(loadHubConfig func() (*rest.Config, error), namespace string) {
+	hubClientMx.Lock()
+	defer hubClientMx.Unlock()
+	hubConfigLoader = loadHubConfig
+	hubNamespace = namespace
+	hubClient = nil
+}
+
+// getHubClient returns the client of the hub and the namespace of the managed cluster on the hub
+func getHubClient() (kubernetes.Interface, string, error) {
+	hubClientMx.Lock()
+	defer hubClientMx.Unlock()
+	if hubClient == nil {
+		if hubConfigLoader == nil || hubNamespace == "" {
+			return nil, "", errors.New("the hub template functions are not enabled")
+		}
+		hubConfig, err := hubConfigLoader()
+		if err != nil {
+			glog.Errorf("Error loading the hub config: %v", err)
+			return nil, "", err
+		}
+		client, err := kubernetes.NewForConfig(hubConfig)
+		if err != nil {
+			glog.Errorf("Error creating the hub client: %v", err)
+			return nil, "", err
+		}
+		hubClient = client
+	}
+	return hubClient, hubNamespace, nil
+}
+
+// resetHubClient drops the client of the hub when the hub rejected its credentials, so that the next read loads
+// the hub config again
+func resetHubClient(client kubernetes.Interface, err error) {
+	// a forbidden read is denied by the RBAC of the hub, the credentials are still valid
+	if !apierrors.IsUnauthorized(err) {
+		return
+	}
+	hubClientMx.Lock()
+	defer hubClientMx.Unlock()
+	// another read may already have created a new client
+	if hubClient == client {
+		glog.Infof("The hub rejected the credentials of its client, the hub config will be loaded again: %v", err)
+		hubClient = nil
+	}
+}
+
+// retrieves the value of the key in the given Secret of the namespace of the managed cluster on the hub, base64
+// encoded like fromSecret
+func fromHubSecret(secretname string, key string) (string, error) {
+	glog.V(2).Infof("fromHubSecret for secretname: %v, key:%v", secretname, key)
+
+	client, namespace, err := getHubClient()
+	if err != nil {
+		return "", err
+	}
+	getObj, getErr := cachedGet(client, "fromHubSecret", []string{namespace, secretname}, func() (interface{},
+		error) {
+		return client.CoreV1().Secrets(namespace).Get(context.TODO(), secretname, metav1.GetOptions{})
+	})
+	if getErr != nil {
+		glog.Errorf("Error getting the hub secret:  %v", getErr)
+		resetHubClient(client, getErr)
+		return "", getErr
+	}
+
+	keyVal := getObj.(*corev1.Secret).Data[key]
+	common.TrackSecretValue(string(keyVal))
+
+	return base64.StdEncoding.EncodeToString(keyVal), nil
+}
+
+// retrieves the value of the key in the given ConfigMap of the namespace of the managed cluster on the hub
+func fromHubConfigMap(cmapname string, key string) (string, error) {
+	glog.V(2).Infof("fromHubConfigMap for configmap name: %v, key:%v", cmapname, key)
+
+	client, namespace, err := getHubClient()
+	if err != nil {
+		return "", err
+	}
+	getObj, getErr := cachedGet(client, "fromHubConfigMap", []string{namespace, cmapname}, func() (interface{},
+		error) {
+		return client.CoreV1().ConfigMaps(namespace).Get(context.TODO(), cmapname, metav1.GetOptions{})
+	})
+	if getErr != nil {
+		glog.Errorf("Error getting the hub configmap:  %v", getErr)
+		resetHubClient(client, getErr)
+		return "", getErr
+	}
+
+	keyVal := getObj.(*corev1.ConfigMap).Data[key]
+	glog.V(2).Infof("Hub configmap Key:%v, Value: %v", key, keyVal)
+
+	return keyVal, nil
+}
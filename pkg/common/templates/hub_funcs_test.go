@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package templates
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestHubFuncs(t *testing.T) {
+	_, _, err := getHubClient()
+	if err == nil || err.Error() != "the hub template functions are not enabled" {
+		t.Fatalf("expected the hub functions to be disabled, got err: %v", err)
+	}
+
+	InitializeHubClient(func() (*rest.Config, error) { return nil, errors.New("no hub config") }, "cluster1")
+	defer InitializeHubClient(nil, "")
+	if _, err := fromHubConfigMap("hubconfigmap", "key"); err == nil || err.Error() != "no hub config" {
+		t.Fatalf("expected the error of the hub config loader, got err: %v", err)
+	}
+
+	// the same objects in the namespace of another cluster must not be read
+	configmaps := []*corev1.ConfigMap{}
+	secrets := []*corev1.Secret{}
+	for _, ns := range []string{"cluster1", "cluster2"} {
+		configmaps = append(configmaps, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "hubconfigmap", Namespace: ns},
+			Data:       map[string]string{"key": ns + "Val"},
+		})
+		secrets = append(secrets, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "hubsecret", Namespace: ns},
+			Data:       map[string][]byte{"key": []byte(ns + "Secret")},
+		})
+	}
+	hubClient = fake.NewSimpleClientset(configmaps[0], configmaps[1], secrets[0], secrets[1])
+
+	testcases := []struct {
+		inputTmpl      string
+		expectedResult string
+		expectedErr    error
+	}{
+		{`value: '{{ fromHubConfigMap "hubconfigmap" "key" }}'`, "value: cluster1Val", nil},
+		{`value: '{{ fromHubConfigMap "hubconfigmap" "idontexist" }}'`, `value: ""`, nil},
+		{`value: '{{ fromHubSecret "hubsecret" "key" | base64dec }}'`, "value: cluster1Secret", nil},
+		{`value: '{{ fromHubConfigMap "idontexist" "key" }}'`, "",
+			errors.New(`template error in function fromHubConfigMap at {{ fromHubConfigMap "idontexist" "key" }}: ` +
+				`configmaps "idontexist" not found`)},
+	}
+
+	for _, test := range testcases {
+		tmplMap, _ := fromYAML(test.inputTmpl)
+		val, err := ResolveTemplate(tmplMap, testClients)
+
+		if err != nil {
+			if test.expectedErr == nil {
+				t.Fatalf(err.Error())
+			}
+			if !strings.EqualFold(test.expectedErr.Error(), err.Error()) {
+				t.Fatalf("expected err: %s got err: %s", test.expectedErr, err)
+			}
+		} else {
+			val, _ := toYAML(val)
+			if val != test.expectedResult {
+				t.Fatalf("expected : %s , got : %s", test.expectedResult, val)
+			}
+		}
+	}
+}
+
+func TestHubFuncsResetClient(t *testing.T) {
+	loads := 0
+	InitializeHubClient(func() (*rest.Config, error) {
+		loads++
+		return &rest.Config{Host: "https://hub.example.com"}, nil
+	}, "cluster1")
+	defer InitializeHubClient(nil, "")
+
+	rejected := fake.NewSimpleClientset()
+	rejected.PrependReactor("get", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewUnauthorized("the credentials were rotated")
+	})
+	hubClient = rejected
+	if _, err := fromHubConfigMap("hubconfigmap", "key"); !apierrors.IsUnauthorized(err) {
+		t.Fatalf("expected an unauthorized error, got err: %v", err)
+	}
+	if hubClient != nil {
+		t.Fatalf("expected the client rejected by the hub to be dropped")
+	}
+
+	// the next read loads the hub config again
+	client, _, err := getHubClient()
+	if err != nil || client == nil || loads != 1 {
+		t.Fatalf("expected the hub config to be loaded again, got loads: %v, err: %v", loads, err)
+	}
+
+	// the other errors keep the client
+	failing := fake.NewSimpleClientset()
+	failing.PrependReactor("get", "secrets", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewServiceUnavailable("the hub is unavailable")
+	})
+	hubClient = failing
+	if _, err := fromHubSecret("hubsecret", "key"); !apierrors.IsServiceUnavailable(err) {
+		t.Fatalf("expected a service unavailable error, got err: %v", err)
+	}
+	if hubClient != failing {
+		t.Fatalf("expected the client to be kept after an error which is not about its credentials")
+	}
+
+	// a read denied by the RBAC of the hub keeps the client
+	forbidden := apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "hubsecret",
+		errors.New("forbidden"))
+	resetHubClient(failing, forbidden)
+	if hubClient != failing {
+		t.Fatalf("expected the client to be kept after a forbidden read")
+	}
+
+	// a client created since the read is not dropped
+	resetHubClient(rejected, apierrors.NewUnauthorized("the credentials were rotated"))
+	if hubClient != failing {
+		t.Fatalf("expected the newer client to be kept")
+	}
+}
@@ -7,44 +7,61 @@ import (
 	"context"
 	base64 "encoding/base64"
 	"github.com/golang/glog"
+	"github.com/open-cluster-management/config-policy-controller/pkg/common"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // retrieves value of the key in the given Secret, namespace
-func fromSecret(namespace string, secretname string, key string) (string, error) {
+func fromSecret(clients Clients, namespace string, secretname string, key string) (string, error) {
 	glog.V(2).Infof("fromSecret for namespace: %v, secretname: %v, key:%v", namespace, secretname, key)
 
-	secretsClient := (*kubeClient).CoreV1().Secrets(namespace)
-	secret, getErr := secretsClient.Get(context.TODO(), secretname, metav1.GetOptions{})
+	kubeClient, err := clients.getKubeClient("fromSecret")
+	if err != nil {
+		return "", err
+	}
+	secretsClient := kubeClient.CoreV1().Secrets(namespace)
+	getObj, getErr := cachedGet(kubeClient, "fromSecret", []string{namespace, secretname}, func() (interface{},
+		error) {
+		return secretsClient.Get(context.TODO(), secretname, metav1.GetOptions{})
+	})
 
 	if getErr != nil {
 		glog.Errorf("Error Getting secret:  %v", getErr)
 		return "", getErr
 	}
-	glog.V(2).Infof("Secret is %v", secret)
+	secret := getObj.(*corev1.Secret)
 
+	// the value is redacted from the logs, the events and the status, and is not logged
 	keyVal := secret.Data[key]
-	glog.V(2).Infof("Secret Key:%v, Value: %v", key, keyVal)
+	common.TrackSecretValue(string(keyVal))
 
 	// when using corev1 secret api, the data is returned decoded ,
 	// re-encododing to be able to use it in the referencing secret
 	sEnc := base64.StdEncoding.EncodeToString(keyVal)
-	glog.V(2).Infof("encoded secret Key:%v, Value: %v", key, sEnc)
 
 	return sEnc, nil
 }
 
 // retrieves value for the key in the given Configmap, namespace
-func fromConfigMap(namespace string, cmapname string, key string) (string, error) {
+func fromConfigMap(clients Clients, namespace string, cmapname string, key string) (string, error) {
 	glog.V(2).Infof("fromConfigMap for namespace: %v, configmap name: %v, key:%v", namespace, cmapname, key)
 
-	configmapsClient := (*kubeClient).CoreV1().ConfigMaps(namespace)
-	configmap, getErr := configmapsClient.Get(context.TODO(), cmapname, metav1.GetOptions{})
+	kubeClient, err := clients.getKubeClient("fromConfigMap")
+	if err != nil {
+		return "", err
+	}
+	configmapsClient := kubeClient.CoreV1().ConfigMaps(namespace)
+	getObj, getErr := cachedGet(kubeClient, "fromConfigMap", []string{namespace, cmapname}, func() (interface{},
+		error) {
+		return configmapsClient.Get(context.TODO(), cmapname, metav1.GetOptions{})
+	})
 
 	if getErr != nil {
 		glog.Errorf("Error getting configmap:  %v", getErr)
 		return "", getErr
 	}
+	configmap := getObj.(*corev1.ConfigMap)
 	glog.V(2).Infof("Configmap is %v", configmap)
 
 	keyVal := configmap.Data[key]
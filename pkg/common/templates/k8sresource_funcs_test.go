@@ -7,6 +7,8 @@ import (
 	"errors"
 	"strings"
 	"testing"
+
+	"github.com/open-cluster-management/config-policy-controller/pkg/common"
 )
 
 func TestFromSecret(t *testing.T) {
@@ -25,7 +27,7 @@ func TestFromSecret(t *testing.T) {
 
 	for _, test := range testcases {
 
-		val, err := fromSecret(test.inputNs, test.inputCMname, test.inputKey)
+		val, err := fromSecret(testClients, test.inputNs, test.inputCMname, test.inputKey)
 
 		if err != nil {
 			if test.expectedErr == nil {
@@ -58,7 +60,7 @@ func TestFromConfigMap(t *testing.T) {
 
 	for _, test := range testcases {
 
-		val, err := fromConfigMap(test.inputNs, test.inputCMname, test.inputKey)
+		val, err := fromConfigMap(testClients, test.inputNs, test.inputCMname, test.inputKey)
 
 		if err != nil {
 			if test.expectedErr == nil {
@@ -74,3 +76,25 @@ func TestFromConfigMap(t *testing.T) {
 		}
 	}
 }
+
+func TestFromSecretRedacted(t *testing.T) {
+	if _, err := fromSecret(testClients, "testns", "testsecret", "secretkey2"); err != nil {
+		t.Fatalf(err.Error())
+	}
+	// the value and its base64 encoding are redacted
+	val := common.Redact("secretkey2Val c2VjcmV0a2V5MlZhbA==")
+	if val != "<redacted> <redacted>" {
+		t.Fatalf("expected : <redacted> <redacted> , got : %s", val)
+	}
+}
+
+func TestFromSecretWithoutClient(t *testing.T) {
+	_, err := ResolveTemplate(map[string]interface{}{
+		"data": `{{ fromSecret "testns" "testsecret" "secretkey1" }}`,
+	}, Clients{})
+	expectedErr := `template error in function fromSecret at {{ fromSecret "testns" "testsecret" "secretkey1" }}: ` +
+		"fromSecret needs a Kubernetes client, none was set to resolve the templates"
+	if err == nil || err.Error() != expectedErr {
+		t.Fatalf("expected err: %s got err: %v", expectedErr, err)
+	}
+}
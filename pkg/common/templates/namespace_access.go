@@ -0,0 +1,87 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package templates
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/open-cluster-management/config-policy-controller/pkg/common"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// the namespaces the template functions can read from, by default they read from any namespace
+var restrictNamespaces bool
+var allowedNamespaces []string
+var namespaceAccessMx sync.RWMutex
+
+// SetNamespaceAccess restricts the template functions to read from the namespace of the policy of the template and
+// from the allowed namespaces, which can be patterns like the namespaceSelector ones, or lets them read from any
+// namespace when restrict is false. The cluster scoped objects can always be read.
+func SetNamespaceAccess(restrict bool, allowed []string) {
+	namespaceAccessMx.Lock()
+	defer namespaceAccessMx.Unlock()
+	restrictNamespaces = restrict
+	allowedNamespaces = allowed
+}
+
+// checkNamespaceAccess returns an error when the templates of a policy of policyNamespace can not read from the
+// namespace, an empty namespace reads from all the namespaces
+func checkNamespaceAccess(policyNamespace string, namespace string) error {
+	namespaceAccessMx.RLock()
+	defer namespaceAccessMx.RUnlock()
+	if !restrictNamespaces {
+		return nil
+	}
+	if namespace != "" && (namespace == policyNamespace || common.IfMatch(namespace, allowedNamespaces, nil)) {
+		return nil
+	}
+	reading := "reading from all the namespaces"
+	if namespace != "" {
+		reading = "reading from namespace " + namespace
+	}
+	readable := allowedNamespaces
+	if policyNamespace != "" {
+		readable = append([]string{policyNamespace}, allowedNamespaces...)
+	}
+	if len(readable) == 0 {
+		return fmt.Errorf("%v is not allowed, the templates can not read namespaced objects", reading)
+	}
+	return fmt.Errorf("%v is not allowed, the templates of the policy can only read from the namespaces: %v",
+		reading, strings.Join(readable, ", "))
+}
+
+// getNamespacedFuncs returns the template functions reading namespaced objects with the clients, restricted to the
+// namespaces the templates of a policy of policyNamespace can read from
+func getNamespacedFuncs(policyNamespace string, clients Clients) map[string]interface{} {
+	return map[string]interface{}{
+		"fromSecret": func(namespace string, secretname string, key string) (string, error) {
+			if err := checkNamespaceAccess(policyNamespace, namespace); err != nil {
+				return "", err
+			}
+			return fromSecret(clients, namespace, secretname, key)
+		},
+		"fromConfigMap": func(namespace string, cmapname string, key string) (string, error) {
+			if err := checkNamespaceAccess(policyNamespace, namespace); err != nil {
+				return "", err
+			}
+			return fromConfigMap(clients, namespace, cmapname, key)
+		},
+		"lookup": func(apiversion string, kind string, namespace string,
+			rsrcname string) (map[string]interface{}, error) {
+			// the cluster scoped objects are not restricted
+			apiResource, err := findAPIResource(clients, schema.FromAPIVersionAndKind(apiversion, kind))
+			if err != nil {
+				return map[string]interface{}{}, err
+			}
+			if apiResource.Namespaced {
+				if err := checkNamespaceAccess(policyNamespace, namespace); err != nil {
+					return map[string]interface{}{}, err
+				}
+			}
+			return lookup(clients, apiversion, kind, namespace, rsrcname)
+		},
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package templates
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNamespaceAccess(t *testing.T) {
+	clients := testClients
+	clients.APIResources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}},
+	}}
+	defer SetNamespaceAccess(false, nil)
+
+	const tmpl = `value: '{{ fromConfigMap "testns" "testconfigmap" "cmkey1" }}'`
+	testcases := []struct {
+		restrict        bool
+		allowed         []string
+		policyNamespace string
+		inputTmpl       string
+		expectedResult  string
+		expectedErr     error
+	}{
+		{false, nil, "other", tmpl, "value: cmkey1Val", nil},
+		{true, nil, "testns", tmpl, "value: cmkey1Val", nil},
+		{true, []string{"test*"}, "other", tmpl, "value: cmkey1Val", nil},
+		{true, []string{"shared"}, "other", tmpl, "",
+			errors.New("template error in function fromConfigMap at " +
+				`{{ fromConfigMap "testns" "testconfigmap" "cmkey1" }}: ` +
+				"reading from namespace testns is not allowed, " +
+				"the templates of the policy can only read from the namespaces: other, shared")},
+		{true, nil, "other", `value: '{{ fromSecret "testns" "testsecret" "secretkey1" }}'`, "",
+			errors.New("template error in function fromSecret at " +
+				`{{ fromSecret "testns" "testsecret" "secretkey1" }}: ` +
+				"reading from namespace testns is not allowed, " +
+				"the templates of the policy can only read from the namespaces: other")},
+		{true, nil, "other", `value: '{{ lookup "v1" "ConfigMap" "testns" "testconfigmap" }}'`, "",
+			errors.New("template error in function lookup at " +
+				`{{ lookup "v1" "ConfigMap" "testns" "testconfigmap" }}: ` +
+				"reading from namespace testns is not allowed, " +
+				"the templates of the policy can only read from the namespaces: other")},
+		{true, nil, "testns", `value: '{{ lookup "v1" "ConfigMap" "" "" }}'`, "",
+			errors.New("template error in function lookup at " +
+				`{{ lookup "v1" "ConfigMap" "" "" }}: ` +
+				"reading from all the namespaces is not allowed, " +
+				"the templates of the policy can only read from the namespaces: testns")},
+		// an undiscovered kind is not read, whatever its namespace
+		{true, nil, "other", `value: '{{ lookup "v1" "ConfigMapp" "testns" "testconfigmap" }}'`, "",
+			errors.New("template error in function lookup at " +
+				`{{ lookup "v1" "ConfigMapp" "testns" "testconfigmap" }}: ` +
+				"the kind ConfigMapp of v1 was not found on the cluster")},
+		{false, nil, "other", `value: '{{ lookup "example.com/v1" "Widget" "testns" "widget" }}'`, "",
+			errors.New("template error in function lookup at " +
+				`{{ lookup "example.com/v1" "Widget" "testns" "widget" }}: ` +
+				"the kind Widget of example.com/v1 was not found on the cluster")},
+	}
+
+	for _, test := range testcases {
+		SetNamespaceAccess(test.restrict, test.allowed)
+		tmplMap, _ := fromYAML(test.inputTmpl)
+		val, err := ResolvePolicyTemplate(tmplMap, test.policyNamespace, clients)
+
+		if err != nil {
+			if test.expectedErr == nil {
+				t.Fatalf(err.Error())
+			}
+			if !strings.EqualFold(test.expectedErr.Error(), err.Error()) {
+				t.Fatalf("expected err: %s got err: %s", test.expectedErr, err)
+			}
+		} else {
+			if test.expectedErr != nil {
+				t.Fatalf("expected err: %s got none", test.expectedErr)
+			}
+			val, _ := toYAML(val)
+			if val != test.expectedResult {
+				t.Fatalf("expected : %s , got : %s", test.expectedResult, val)
+			}
+		}
+	}
+}
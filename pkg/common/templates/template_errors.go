@@ -0,0 +1,111 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package templates
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// TemplateError is an error parsing or executing a template, with the failing action of the template. The position
+// of the action is not reported since the template is parsed from the re-marshaled YAML of the object definition,
+// whose lines and key order differ from the policy.
+type TemplateError struct {
+	// Function is the template function that failed or is not defined, if any
+	Function string
+	// Action is the failing action of the template, without its delimiters, if it is known
+	Action string
+	// Message is the cause of the error, without the action
+	Message string
+	Err     error
+}
+
+func (e *TemplateError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("template error")
+	if e.Function != "" {
+		sb.WriteString(" in function " + e.Function)
+	}
+	if e.Action != "" {
+		sb.WriteString(" at {{ " + e.Action + " }}")
+	}
+	sb.WriteString(": " + e.Message)
+	return sb.String()
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// the errors of text/template, as in
+// template: tmpl:3:11: executing "tmpl" at <fromSecret "ns" "name" "key">: error calling fromSecret: not found
+// and template: tmpl:1: function "blah" not defined
+var (
+	templateErrorRe     = regexp.MustCompile(`(?s)^template: [^:]*:(\d+)(?::(\d+))?: (.*)$`)
+	executingErrorRe    = regexp.MustCompile(`(?s)^executing "[^"]*" at <(.*?)>: (.*)$`)
+	callingErrorRe      = regexp.MustCompile(`(?s)^error calling (\w+): (.*)$`)
+	undefinedFunctionRe = regexp.MustCompile(`^function "(\w+)" not defined$`)
+	actionFunctionRe    = regexp.MustCompile(`^(\w+)`)
+	stringArgumentRe    = regexp.MustCompile("\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`")
+)
+
+// newTemplateError returns the TemplateError of an error of text/template, templateStr is the parsed template so
+// that the action of an undefined function can be found
+func newTemplateError(err error, templateStr string) error {
+	tmplErr := &TemplateError{Message: err.Error(), Err: err}
+	match := templateErrorRe.FindStringSubmatch(err.Error())
+	if match == nil {
+		return tmplErr
+	}
+	line, _ := strconv.Atoi(match[1])
+	tmplErr.Message = match[3]
+
+	if execMatch := executingErrorRe.FindStringSubmatch(tmplErr.Message); execMatch != nil {
+		tmplErr.Action = execMatch[1]
+		tmplErr.Message = execMatch[2]
+		if callMatch := callingErrorRe.FindStringSubmatch(tmplErr.Message); callMatch != nil {
+			tmplErr.Function = callMatch[1]
+			tmplErr.Message = callMatch[2]
+		} else if actionMatch := actionFunctionRe.FindStringSubmatch(execMatch[1]); actionMatch != nil {
+			tmplErr.Function = actionMatch[1]
+		}
+	} else if undefinedMatch := undefinedFunctionRe.FindStringSubmatch(tmplErr.Message); undefinedMatch != nil {
+		tmplErr.Function = undefinedMatch[1]
+		lines := strings.Split(templateStr, "\n")
+		if line > 0 && line <= len(lines) {
+			tmplErr.Action = findAction(lines[line-1], tmplErr.Function)
+		}
+	}
+	return tmplErr
+}
+
+// findAction returns the first action of a line of a template calling the function, without its delimiters,
+// or an empty string when it is not found
+func findAction(line string, function string) string {
+	for {
+		start := strings.Index(line, "{{")
+		if start < 0 {
+			return ""
+		}
+		end := strings.Index(line[start:], "}}")
+		if end < 0 {
+			return ""
+		}
+		action := strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(line[start+2:start+end], "-"), "-"))
+		// the string arguments are not function names
+		for _, field := range strings.FieldsFunc(stringArgumentRe.ReplaceAllString(action, ""), isNotIdentifier) {
+			if field == function {
+				return action
+			}
+		}
+		line = line[start+end+2:]
+	}
+}
+
+// isNotIdentifier returns true for the runes that can't be part of the name of a template function
+func isNotIdentifier(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+}
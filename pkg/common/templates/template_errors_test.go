@@ -0,0 +1,61 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package templates
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewTemplateError(t *testing.T) {
+	const templateStr = "data:\n  key: '{{ blah }}'\n  other: '{{ \"blah\" }}{{- blah 1 -}}'"
+	testcases := []struct {
+		input    error
+		expected TemplateError
+	}{
+		{
+			errors.New(`template: tmpl:3:11: executing "tmpl" at <fromSecret "ns" "name" "key">: ` +
+				`error calling fromSecret: secrets "name" not found`),
+			TemplateError{
+				Function: "fromSecret", Action: `fromSecret "ns" "name" "key"`, Message: `secrets "name" not found`,
+			},
+		},
+		{
+			errors.New(`template: tmpl:2:9: executing "tmpl" at <index .data "key">: error calling index: ` +
+				`can't index item of type string`),
+			TemplateError{Function: "index", Action: `index .data "key"`, Message: "can't index item of type string"},
+		},
+		{
+			errors.New(`template: tmpl:1:5: executing "tmpl" at <.foo>: can't evaluate field foo in type string`),
+			TemplateError{Action: ".foo", Message: "can't evaluate field foo in type string"},
+		},
+		{
+			errors.New(`template: tmpl:3: function "blah" not defined`),
+			TemplateError{Function: "blah", Action: "blah 1", Message: `function "blah" not defined`},
+		},
+		{
+			errors.New("template: tmpl:1: unexpected EOF"),
+			TemplateError{Message: "unexpected EOF"},
+		},
+		{
+			errors.New("not a template error"),
+			TemplateError{Message: "not a template error"},
+		},
+	}
+
+	for _, test := range testcases {
+		err := newTemplateError(test.input, templateStr)
+		tmplErr, ok := err.(*TemplateError)
+		if !ok {
+			t.Fatalf("expected a TemplateError, got %T", err)
+		}
+		test.expected.Err = test.input
+		if *tmplErr != test.expected {
+			t.Fatalf("expected : %+v , got : %+v", test.expected, *tmplErr)
+		}
+		if !errors.Is(err, test.input) {
+			t.Fatalf("expected the TemplateError to wrap %v", test.input)
+		}
+	}
+}
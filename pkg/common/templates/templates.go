@@ -4,11 +4,14 @@
 package templates
 
 import (
+	"errors"
+	"fmt"
 	"github.com/golang/glog"
+	"github.com/open-cluster-management/config-policy-controller/pkg/common"
 	"github.com/spf13/cast"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 	"regexp"
 	"sigs.k8s.io/yaml"
 	"strconv"
@@ -16,24 +19,36 @@ import (
 	"text/template"
 )
 
-var kubeClient *kubernetes.Interface
-var kubeConfig *rest.Config
-var kubeAPIResourceList []*metav1.APIResourceList
+// Clients are the clients the template functions read the cluster with, they are passed with each template to
+// resolve so that templates can be resolved for different clusters
+type Clients struct {
+	// Kube reads the Secrets and ConfigMaps of fromSecret and fromConfigMap
+	Kube kubernetes.Interface
+	// Dynamic reads the objects of lookup and fromClusterClaim
+	Dynamic dynamic.Interface
+	// APIResources maps the kinds of lookup and fromClusterClaim to their resources
+	APIResources []*metav1.APIResourceList
+}
 
-func InitializeKubeClient(kClient *kubernetes.Interface, kConfig *rest.Config) {
-	kubeClient = kClient
-	kubeConfig = kConfig
+// getKubeClient returns the client reading the Secrets and ConfigMaps, or an error when there is none
+func (c Clients) getKubeClient(function string) (kubernetes.Interface, error) {
+	if c.Kube == nil {
+		return nil, fmt.Errorf("%v needs a Kubernetes client, none was set to resolve the templates", function)
+	}
+	return c.Kube, nil
 }
 
-//If this is set, template processing will not try to rediscover
-// the apiresourcesList needed for dynamic client/ gvk look
-func SetAPIResources(apiresList []*metav1.APIResourceList) {
-	kubeAPIResourceList = apiresList
+// getDynamicInterface returns the client reading the objects, or an error when there is none
+func (c Clients) getDynamicInterface() (dynamic.Interface, error) {
+	if c.Dynamic == nil {
+		return nil, errors.New("no dynamic client was set to resolve the templates")
+	}
+	return c.Dynamic, nil
 }
 
 // just does a simple check for {{ string to indicate if it has a template
 func HasTemplate(templateStr string) bool {
-	glog.V(2).Infof("hasTemplate template str:  %v", templateStr)
+	glog.V(2).Infof("hasTemplate template str:  %v", common.Redact(templateStr))
 
 	hasTemplate := false
 	if strings.Contains(templateStr, "{{") {
@@ -44,58 +59,132 @@ func HasTemplate(templateStr string) bool {
 	return hasTemplate
 }
 
-// Main Template Processing func
-func ResolveTemplate(tmplMap interface{}) (interface{}, error) {
-
-	glog.V(2).Infof("ResolveTemplate for: %v", tmplMap)
-
-	// Build Map of supported template functions
+// getFuncMap returns the map of the supported template functions, for the templates of a policy of
+// policyNamespace read with the clients
+func getFuncMap(policyNamespace string, clients Clients) template.FuncMap {
 	funcMap := template.FuncMap{
-		"fromSecret":       fromSecret,
-		"fromConfigMap":    fromConfigMap,
-		"fromClusterClaim": fromClusterClaim,
-		"lookup":           lookup,
+		"fromClusterClaim": func(claimname string) (string, error) {
+			return fromClusterClaim(clients, claimname)
+		},
+		"fromHubSecret":    fromHubSecret,
+		"fromHubConfigMap": fromHubConfigMap,
 		"base64enc":        base64encode,
 		"base64dec":        base64decode,
 		"indent":           indent,
 		"atoi":             atoi,
 		"toInt":            toInt,
 		"toBool":           toBool,
+		"trim":             strings.TrimSpace,
+		"trimPrefix":       trimPrefix,
+		"trimSuffix":       trimSuffix,
+		"replace":          replace,
+		"split":            split,
+		"join":             join,
+		"lower":            strings.ToLower,
+		"upper":            strings.ToUpper,
+		"contains":         contains,
+		"hasPrefix":        hasPrefix,
+		"hasSuffix":        hasSuffix,
+		"default":          defaultValue,
+		"regexMatch":       regexMatch,
+		"regexReplaceAll":  regexReplaceAll,
+		"sha256sum":        sha256sum,
+		"dict":             dict,
+		"list":             list,
+		"toJSON":           toJSON,
+		"toYAML":           toYAML,
 	}
+	for name, function := range getNamespacedFuncs(policyNamespace, clients) {
+		funcMap[name] = function
+	}
+	return funcMap
+}
 
-	// create template processor and Initialize function map
-	tmpl := template.New("tmpl").Funcs(funcMap)
-
+// parseTemplate converts the template map to a string and parses it with the supported functions
+func parseTemplate(tmplMap interface{}, policyNamespace string, clients Clients) (*template.Template, string,
+	error) {
 	//convert the interface to yaml to string
 	// ext.raw is jsonMarshalled data which the template processor is not accepting
 	// so marshalling  unmarshalled(ext.raw) to yaml to string
 
 	templateStr, err := toYAML(tmplMap)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
-	glog.V(2).Infof("Initial template str to resolve : %v ", templateStr)
+	return parseTemplateString(templateStr, policyNamespace, clients)
+}
+
+// parseTemplateString parses the YAML template string with the supported functions
+func parseTemplateString(templateStr string, policyNamespace string, clients Clients) (*template.Template, string,
+	error) {
+	// create template processor and Initialize function map
+	tmpl := template.New("tmpl").Funcs(getFuncMap(policyNamespace, clients))
+
+	glog.V(2).Infof("Initial template str to resolve : %v ", common.Redact(templateStr))
 
 	//process for int or bool
 	if strings.Contains(templateStr, "toInt") || strings.Contains(templateStr, "toBool") {
 		templateStr = processForDataTypes(templateStr)
 	}
 
-	tmpl, err = tmpl.Parse(templateStr)
+	tmpl, err := tmpl.Parse(templateStr)
 	if err != nil {
-		glog.Errorf("error parsing template map %v,\n template str %v,\n error: %v", tmplMap, templateStr, err)
-		return "", err
+		glog.Errorf("error parsing template str %v,\n error: %v", common.Redact(templateStr), err)
+		return nil, templateStr, newTemplateError(err, templateStr)
 	}
+	return tmpl, templateStr, nil
+}
 
+// executeTemplate executes the parsed template and returns the resolved string
+func executeTemplate(tmpl *template.Template, templateStr string) (string, error) {
 	var buf strings.Builder
-	err = tmpl.Execute(&buf, "")
+	err := tmpl.Execute(&buf, "")
 	if err != nil {
-		glog.Errorf("error executing the template map %v,\n template str %v,\n error: %v", tmplMap, templateStr, err)
-		return "", err
+		glog.Errorf("error executing the template str %v,\n error: %v", common.Redact(templateStr), err)
+		return "", newTemplateError(err, templateStr)
 	}
 
 	resolvedTemplateStr := buf.String()
-	glog.V(2).Infof("resolved template str : %v ", resolvedTemplateStr)
+	glog.V(2).Infof("resolved template str : %v ", common.Redact(resolvedTemplateStr))
+	return resolvedTemplateStr, nil
+}
+
+// ValidateTemplate checks that the template parses and only uses supported functions, without resolving it
+func ValidateTemplate(tmplMap interface{}) error {
+	_, _, err := parseTemplate(tmplMap, "", Clients{})
+	return err
+}
+
+// ValidateRawTemplate checks that the YAML template string parses and only uses supported functions, without
+// resolving it
+func ValidateRawTemplate(templateStr string) error {
+	_, _, err := parseTemplateString(templateStr, "", Clients{})
+	return err
+}
+
+// Main Template Processing func, the template functions read the cluster with the clients and only from the
+// namespaces allowed by SetNamespaceAccess for all the policies, use ResolvePolicyTemplate to resolve the template
+// of a policy
+func ResolveTemplate(tmplMap interface{}, clients Clients) (interface{}, error) {
+	return ResolvePolicyTemplate(tmplMap, "", clients)
+}
+
+// ResolvePolicyTemplate resolves the template of a policy of policyNamespace with the clients, the template
+// functions can also read from the namespace of the policy when their namespaces are restricted by
+// SetNamespaceAccess
+func ResolvePolicyTemplate(tmplMap interface{}, policyNamespace string, clients Clients) (interface{}, error) {
+
+	glog.V(2).Infof("ResolveTemplate for: %v", common.Redact(fmt.Sprint(tmplMap)))
+
+	tmpl, templateStr, err := parseTemplate(tmplMap, policyNamespace, clients)
+	if err != nil {
+		return "", err
+	}
+
+	resolvedTemplateStr, err := executeTemplate(tmpl, templateStr)
+	if err != nil {
+		return "", err
+	}
 	//unmarshall before returning
 
 	resolvedTemplateIntf, err := fromYAML(resolvedTemplateStr)
@@ -106,12 +195,22 @@ func ResolveTemplate(tmplMap interface{}) (interface{}, error) {
 	return resolvedTemplateIntf, nil
 }
 
+// ResolveRawTemplate resolves a YAML template string of a policy of policyNamespace with the clients, such as the
+// object-templates-raw of a policy, and returns the resolved YAML string
+func ResolveRawTemplate(templateStr string, policyNamespace string, clients Clients) (string, error) {
+	tmpl, templateStr, err := parseTemplateString(templateStr, policyNamespace, clients)
+	if err != nil {
+		return "", err
+	}
+	return executeTemplate(tmpl, templateStr)
+}
+
 // fromYAML converts a YAML document into a map[string]interface{}.
 func fromYAML(str string) (map[string]interface{}, error) {
 	m := map[string]interface{}{}
 
 	if err := yaml.Unmarshal([]byte(str), &m); err != nil {
-		glog.Errorf("error parsing the YAML  the template str %v , \n %v ", str, err)
+		glog.Errorf("error parsing the YAML  the template str %v , \n %v ", common.Redact(str), err)
 		return m, err
 	}
 	return m, nil
@@ -121,7 +220,7 @@ func fromYAML(str string) (map[string]interface{}, error) {
 func toYAML(v interface{}) (string, error) {
 	data, err := yaml.Marshal(v)
 	if err != nil {
-		glog.Errorf("error parsing the YAML the template map %v , \n %v ", v, err)
+		glog.Errorf("error parsing the YAML the template map %v , \n %v ", common.Redact(fmt.Sprint(v)), err)
 		return "", err
 	}
 
@@ -143,10 +242,10 @@ func processForDataTypes(str string) string {
 	glog.V(2).Infof("\n Pattern: %v\n", re.String())
 
 	submatchall := re.FindAllStringSubmatch(str, -1)
-	glog.V(2).Infof("\n All Submatches:\n%v", submatchall)
+	glog.V(2).Infof("\n All Submatches:\n%v", common.Redact(fmt.Sprint(submatchall)))
 
 	processeddata := re.ReplaceAllString(str, ": $1")
-	glog.V(2).Infof("\n processed data :\n%v", processeddata)
+	glog.V(2).Infof("\n processed data :\n%v", common.Redact(processeddata))
 
 	return processeddata
 }
@@ -13,9 +13,13 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	fake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/yaml"
 	"strings"
 )
 
+// testClients are the clients of the templates resolved by the tests
+var testClients Clients
+
 func TestMain(m *testing.M) {
 
 	var simpleClient kubernetes.Interface = fake.NewSimpleClientset()
@@ -54,7 +58,7 @@ func TestMain(m *testing.M) {
 	}
 	simpleClient.CoreV1().ConfigMaps(testns).Create(context.TODO(), &configmap, metav1.CreateOptions{})
 
-	InitializeKubeClient(&simpleClient, nil)
+	testClients = Clients{Kube: simpleClient}
 
 	exitVal := m.Run()
 	os.Exit(exitVal)
@@ -91,7 +95,7 @@ func TestResolveTemplate(t *testing.T) {
 		{
 			`test: '{{ blah "asdf"  }}'`,
 			"",
-			errors.New("template: tmpl:1: function \"blah\" not defined"),
+			errors.New("template error in function blah at {{ blah \"asdf\" }}: function \"blah\" not defined"),
 		},
 	}
 
@@ -99,7 +103,7 @@ func TestResolveTemplate(t *testing.T) {
 
 		//unmarshall to Interface
 		tmplMap, _ := fromYAML(test.inputTmpl)
-		val, err := ResolveTemplate(tmplMap)
+		val, err := ResolveTemplate(tmplMap, testClients)
 
 		if err != nil {
 			if test.expectedErr == nil {
@@ -117,6 +121,71 @@ func TestResolveTemplate(t *testing.T) {
 	}
 }
 
+func TestValidateTemplate(t *testing.T) {
+	testcases := []struct {
+		inputTmpl   string
+		expectedErr error
+	}{
+		// not resolved, so the secret does not need to exist
+		{`data: '{{ fromSecret "testns" "missing" "secretkey1" }}'`, nil},
+		{`test: '{{ blah "asdf"  }}'`,
+			errors.New("template error in function blah at {{ blah \"asdf\" }}: function \"blah\" not defined")},
+		{`test: '{{ if true }}value'`,
+			errors.New("template error: unexpected EOF")},
+	}
+
+	for _, test := range testcases {
+		tmplMap, _ := fromYAML(test.inputTmpl)
+		err := ValidateTemplate(tmplMap)
+		if test.expectedErr == nil {
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+		} else if err == nil || !strings.EqualFold(test.expectedErr.Error(), err.Error()) {
+			t.Fatalf("expected err: %s got err: %v", test.expectedErr, err)
+		}
+	}
+}
+
+func TestResolveRawTemplate(t *testing.T) {
+	const rawTmpl = `{{ range (list "ns1" "ns2") }}
+- complianceType: musthave
+  objectDefinition:
+    kind: ConfigMap
+    metadata:
+      name: config
+      namespace: {{ . }}
+    data:
+      key: '{{ fromConfigMap "testns" "testconfigmap" "cmkey1" }}'
+{{ end }}`
+
+	val, err := ResolveRawTemplate(rawTmpl, "testns", testClients)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	objectTemplates := []map[string]interface{}{}
+	if err := yaml.Unmarshal([]byte(val), &objectTemplates); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(objectTemplates) != 2 {
+		t.Fatalf("expected 2 object templates, got : %s", val)
+	}
+	for i, namespace := range []string{"ns1", "ns2"} {
+		definition, _ := toYAML(objectTemplates[i]["objectDefinition"])
+		expected := "data:\n  key: cmkey1Val\nkind: ConfigMap\nmetadata:\n  name: config\n  namespace: " +
+			namespace
+		if definition != expected {
+			t.Fatalf("expected : %s , got : %s", expected, definition)
+		}
+	}
+
+	_, err = ResolveRawTemplate(`{{ range (list "ns1") }}{{ blah }}{{ end }}`, "testns", testClients)
+	expectedErr := `template error in function blah at {{ blah }}: function "blah" not defined`
+	if err == nil || err.Error() != expectedErr {
+		t.Fatalf("expected err: %s got err: %v", expectedErr, err)
+	}
+}
+
 func TestHasTemplate(t *testing.T) {
 	testcases := []struct {
 		input  string
@@ -0,0 +1,99 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/golang/glog"
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/open-cluster-management/config-policy-controller/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+var reasonAssertionsFailed = "Resource found but failed the assertions"
+
+// getAssertionFailures returns the assertion failures of each of the named objects failing an assertion of the
+// object template, among the objects listed for it
+func getAssertionFailures(objectT *policyv1.ObjectTemplate, names []string,
+	objects []unstructured.Unstructured) map[string][]policyv1.AssertionFailure {
+	failures := map[string][]policyv1.AssertionFailure{}
+	if len(objectT.Assertions) == 0 {
+		return failures
+	}
+	named := map[string]bool{}
+	for _, name := range names {
+		named[name] = true
+	}
+	for _, object := range objects {
+		if !named[object.GetName()] {
+			continue
+		}
+		if objectFailures := common.EvaluateAssertions(objectT.Assertions, object.Object); len(objectFailures) > 0 {
+			failures[object.GetName()] = objectFailures
+		}
+	}
+	return failures
+}
+
+// getObjectAssertionFailures gets the named object of an object template from the cluster and returns its
+// assertion failures, every assertion fails when the object can not be read
+func getObjectAssertionFailures(objectT *policyv1.ObjectTemplate, rsrc schema.GroupVersionResource,
+	namespace string, namespaced bool, name string, dclient dynamic.Interface) []policyv1.AssertionFailure {
+	if len(objectT.Assertions) == 0 {
+		return nil
+	}
+	var res dynamic.ResourceInterface = dclient.Resource(rsrc)
+	if namespaced {
+		res = dclient.Resource(rsrc).Namespace(namespace)
+	}
+	object, err := res.Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		glog.Errorf("error getting the object %v to check its assertions: %v", name, err)
+		failures := []policyv1.AssertionFailure{}
+		for _, assertion := range objectT.Assertions {
+			failures = append(failures, policyv1.AssertionFailure{Path: assertion.Path, Operator: assertion.Operator,
+				Value: assertion.Value, Message: fmt.Sprintf("the object could not be read: %v", err)})
+		}
+		return failures
+	}
+	return common.EvaluateAssertions(objectT.Assertions, object.Object)
+}
+
+// getSingleObjAssertionFailures returns the assertion failures of the object of handleSingleObj, the failures
+// of a listed object are set in its data, a named object is read again once updated
+func getSingleObjAssertionFailures(objectT *policyv1.ObjectTemplate, rsrc schema.GroupVersionResource,
+	dclient dynamic.Interface, data map[string]interface{}) []policyv1.AssertionFailure {
+	if failures, listed := data["assertionFailures"].([]policyv1.AssertionFailure); listed {
+		return failures
+	}
+	return getObjectAssertionFailures(objectT, rsrc, data["namespace"].(string), data["namespaced"].(bool),
+		data["name"].(string), dclient)
+}
+
+// describeAssertionFailures returns a message listing the assertion failures
+func describeAssertionFailures(failures []policyv1.AssertionFailure) string {
+	descriptions := []string{}
+	for _, failure := range failures {
+		description := fmt.Sprintf("%v %v", failure.Path, failure.Operator)
+		if failure.Value != "" {
+			description += " " + failure.Value
+		}
+		switch {
+		case failure.Message != "":
+			description += " (" + failure.Message + ")"
+		case failure.Actual != "":
+			description += " (actual " + failure.Actual + ")"
+		case !strings.EqualFold(string(failure.Operator), string(policyv1.AssertDoesNotExist)):
+			description += " (missing)"
+		}
+		descriptions = append(descriptions, description)
+	}
+	return strings.Join(descriptions, ", ")
+}
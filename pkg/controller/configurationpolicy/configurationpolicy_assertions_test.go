@@ -0,0 +1,76 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"testing"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newAssertionIngress(name string, hosts ...string) *unstructured.Unstructured {
+	rules := []interface{}{}
+	for _, host := range hosts {
+		rules = append(rules, map[string]interface{}{"host": host})
+	}
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "networking.k8s.io/v1",
+		"kind":       "Ingress",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+		"spec":       map[string]interface{}{"rules": rules},
+	}}
+}
+
+func TestGetAssertionFailures(t *testing.T) {
+	objectT := &policyv1.ObjectTemplate{Assertions: []policyv1.Assertion{
+		{Path: ".spec.rules[*].host", Operator: policyv1.AssertHasSuffix, Value: ".corp.example.com"},
+	}}
+	objects := []unstructured.Unstructured{
+		*newAssertionIngress("internal", "app.corp.example.com"),
+		*newAssertionIngress("public", "app.corp.example.com", "app.example.org"),
+		*newAssertionIngress("unnamed", "app.example.org"),
+	}
+
+	// only the named objects are checked
+	failures := getAssertionFailures(objectT, []string{"internal", "public"}, objects)
+	assert.Equal(t, map[string][]policyv1.AssertionFailure{"public": {{Path: "spec.rules[1].host",
+		Operator: policyv1.AssertHasSuffix, Value: ".corp.example.com", Actual: `"app.example.org"`}}}, failures)
+	assert.Empty(t, getAssertionFailures(&policyv1.ObjectTemplate{}, []string{"public"}, objects))
+}
+
+func TestGetObjectAssertionFailures(t *testing.T) {
+	rsrc := schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"}
+	dclient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newAssertionIngress("public", "app.corp.example.com", "app.example.org"))
+	objectT := &policyv1.ObjectTemplate{Assertions: []policyv1.Assertion{
+		{Path: ".spec.rules[*].host", Operator: policyv1.AssertHasSuffix, Value: ".corp.example.com"},
+	}}
+
+	assert.Equal(t, []policyv1.AssertionFailure{{Path: "spec.rules[1].host", Operator: policyv1.AssertHasSuffix,
+		Value: ".corp.example.com", Actual: `"app.example.org"`}},
+		getObjectAssertionFailures(objectT, rsrc, "default", true, "public", dclient))
+	// the objects that can't be read fail the assertions
+	assert.Equal(t, []policyv1.AssertionFailure{{Path: ".spec.rules[*].host", Operator: policyv1.AssertHasSuffix,
+		Value: ".corp.example.com", Message: `the object could not be read: ingresses.networking.k8s.io ` +
+			`"missing" not found`}},
+		getObjectAssertionFailures(objectT, rsrc, "default", true, "missing", dclient))
+}
+
+func TestDescribeAssertionFailures(t *testing.T) {
+	assert.Equal(t, "spec.replicas GreaterThanOrEqual 2 (actual 1), "+
+		"spec.containers[0].resources.limits.memory Exists (missing), "+
+		"metadata.name LessThan 2 (the value app is not a number or a quantity), spec.paused DoesNotExist (actual true)",
+		describeAssertionFailures([]policyv1.AssertionFailure{
+			{Path: "spec.replicas", Operator: policyv1.AssertGreaterThanOrEqual, Value: "2", Actual: "1"},
+			{Path: "spec.containers[0].resources.limits.memory", Operator: policyv1.AssertExists},
+			{Path: "metadata.name", Operator: policyv1.AssertLessThan, Value: "2", Actual: `"app"`,
+				Message: "the value app is not a number or a quantity"},
+			{Path: "spec.paused", Operator: policyv1.AssertDoesNotExist, Actual: "true"},
+		}))
+}
@@ -27,6 +27,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -38,12 +39,16 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
+	"sigs.k8s.io/yaml"
 
 	"k8s.io/client-go/restmapper"
 )
 
 const controllerName string = "configuration-policy-controller"
 
+// fieldManager owns the fields of the objects created and updated by enforced policies
+const fieldManager string = "config-policy-controller"
+
 var log = logf.Log.WithName(controllerName)
 
 // availablePolicies is a cach all all available polices
@@ -61,19 +66,26 @@ var eventWarning = "Warning"
 var eventFmtStr = "policy: %s/%s"
 var plcFmtStr = "policy: %s"
 
+// complianceHistorySize is the number of compliance changes kept for each object template
+var complianceHistorySize uint = 10
+
 var reasonWantFoundExists = "Resource found as expected"
 var reasonWantFoundNoMatch = "Resource found but does not match"
 var reasonWantFoundDNE = "Resource not found but should exist"
 var reasonWantNotFoundExists = "Resource found but should not exist"
 var reasonWantNotFoundDNE = "Resource not found as expected"
+var reasonFieldsNotFound = "Resource found without the forbidden fields"
+var reasonFieldsFound = "Resource found with forbidden fields"
 
 const getObjError = "object `%v` cannot be retrieved from the api server\n"
 const convertJSONError = "Error converting updated %s to JSON: %s"
 
 var config *rest.Config
 
-//Mx for making the map thread safe
-var Mx sync.RWMutex
+// dynamicClient is created from config on the first evaluation and shared by the evaluations, so that the
+// template lookups cached for it are shared by the policies
+var dynamicClient dynamic.Interface
+var dynamicClientMx sync.Mutex
 
 //MxUpdateMap for making the map thread safe
 var MxUpdateMap sync.RWMutex
@@ -127,7 +139,12 @@ func Initialize(kubeconfig *rest.Config, clientset *kubernetes.Clientset,
 	EventOnParent = strings.ToLower(eventParent)
 	recorder, _ = common.CreateRecorder(*KubeClient, controllerName)
 	config = kubeconfig
-	templates.InitializeKubeClient(kubeClient, kubeconfig)
+}
+
+// InitializeComplianceHistory sets the number of compliance changes kept in the history of each object template,
+// 0 disables the history
+func InitializeComplianceHistory(size uint) {
+	complianceHistorySize = size
 }
 
 //InitializeClient helper function to initialize kubeclient
@@ -169,6 +186,13 @@ func (r *ReconcileConfigurationPolicy) Reconcile(request reconcile.Request) (rec
 			// Return and don't requeue
 			reqLogger.Info("Configuration policy was deleted, removing it...")
 			handleRemovingPolicy(request.NamespacedName.Name)
+			if objWatcher != nil {
+				objWatcher.removePolicy(request.NamespacedName.String())
+			}
+			lastEvaluationsMx.Lock()
+			delete(lastEvaluations, request.NamespacedName.String())
+			lastEvaluationsMx.Unlock()
+			removePolicyMetrics(request.Namespace, request.Name)
 			return reconcile.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
@@ -176,24 +200,80 @@ func (r *ReconcileConfigurationPolicy) Reconcile(request reconcile.Request) (rec
 		return reconcile.Result{}, err
 	}
 
+	if instance.GetDeletionTimestamp() != nil {
+		if !hasFinalizer(instance, pruneFinalizer) {
+			return reconcile.Result{}, nil
+		}
+		reqLogger.Info("Configuration policy is being deleted, pruning its objects...")
+		handleRemovingPolicy(request.NamespacedName.Name)
+		if objWatcher != nil {
+			objWatcher.removePolicy(request.NamespacedName.String())
+		}
+		dclient, err := getDynamicClient()
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		err = pruneObjects(instance, dclient)
+		if err != nil {
+			reqLogger.Info("Failed to prune the objects of the policy", "err", err)
+			return reconcile.Result{}, err
+		}
+		instance.SetFinalizers(removeFinalizer(instance, pruneFinalizer))
+		err = r.client.Update(context.TODO(), instance)
+		if err != nil {
+			reqLogger.Info("Failed to remove the finalizer of the policy", "err", err)
+			return reconcile.Result{}, err
+		}
+		return reconcile.Result{}, nil
+	}
+
+	// the finalizer keeps the policy until its objects are pruned
+	if shouldPrune(instance) != hasFinalizer(instance, pruneFinalizer) {
+		if shouldPrune(instance) {
+			instance.SetFinalizers(append(instance.GetFinalizers(), pruneFinalizer))
+		} else {
+			instance.SetFinalizers(removeFinalizer(instance, pruneFinalizer))
+		}
+		err = r.client.Update(context.TODO(), instance)
+		if err != nil {
+			reqLogger.Info("Failed to update the finalizers of the policy", "err", err)
+			return reconcile.Result{}, err
+		}
+	}
+
 	reqLogger.Info("Configuration policy was found, adding it...")
+	previous := getPolicyByKey(policyKey(instance))
 	err = handleAddingPolicy(instance)
 	if err != nil {
 		reqLogger.Info("Failed to handleAddingPolicy", "err", err)
 		return reconcile.Result{}, err
 	}
+	// evaluate the new policy or its updated spec without waiting for the next resync, the status updates of the
+	// evaluations don't change the generation of the policy
+	if objWatcher != nil && (previous == nil || previous.GetGeneration() != instance.GetGeneration()) {
+		objWatcher.trigger(policyKey(instance))
+	}
 	reqLogger.Info("Reconcile complete.")
 	return reconcile.Result{}, nil
 }
 
-// PeriodicallyExecConfigPolicies always check status
-func PeriodicallyExecConfigPolicies(freq uint, test bool) {
+// PeriodicallyExecConfigPolicies always check status, evaluating up to concurrency policies in parallel
+func PeriodicallyExecConfigPolicies(freq uint, concurrency uint, test bool) {
 	// var plcToUpdateMap map[string]*policyv1.ConfigurationPolicy
+	nextResync := time.Now()
 	for {
 		start := time.Now()
-		printMap(availablePolicies.PolicyMap)
+		// every policy is evaluated on a resync, in between only the policies whose evaluation interval elapsed
+		resync := !start.Before(nextResync)
+		if resync {
+			nextResync = start.Add(time.Duration(freq) * time.Second)
+			// the list types of the kinds are loaded again, the CRDs may have changed
+			resetKindSchemas()
+		}
+		policyMap := availablePolicies.GetAll()
+		printMap(policyMap)
 		flattenedPolicyList := map[string]*policyv1.ConfigurationPolicy{}
-		for _, policy := range availablePolicies.PolicyMap {
+		for _, policy := range policyMap {
 			key := fmt.Sprintf("%s/%s", policy.GetName(), policy.GetResourceVersion())
 			if _, ok := flattenedPolicyList[key]; ok {
 				continue
@@ -209,31 +289,46 @@ func PeriodicallyExecConfigPolicies(freq uint, test bool) {
 		if apiresourcelistErr != nil {
 			skipLoop = true
 			glog.Errorf("Failed to retrieve apiresourcelist with err: %v", apiresourcelistErr)
+			discoveryFailuresCounter.Inc()
 		}
 		apigroups, apigroupsErr := restmapper.GetAPIGroupResources(dd)
 
 		if !skipLoop && apigroupsErr != nil {
 			skipLoop = true
 			glog.Errorf("Failed to retrieve apigroups with err: %v", apigroupsErr)
+			discoveryFailuresCounter.Inc()
 
 		}
 		if skipLoop {
 			glog.Errorf("Unexpected failure detected. You api server might not be stable. Waiting for next loop...")
 		} else {
 			//flattenedpolicylist only contains 1 of each policy instance
+			duePolicies := []*policyv1.ConfigurationPolicy{}
 			for _, policy := range flattenedPolicyList {
-				Mx.Lock()
-				handleObjectTemplates(*policy, apiresourcelist, apigroups)
-				Mx.Unlock()
+				if shouldEvaluatePolicy(policy, resync) {
+					duePolicies = append(duePolicies, policy)
+				}
+			}
+			// the secret values are forgotten when no policy reads them again, which is only known once every
+			// policy is evaluated again. The values read by a policy are redacted from the others, so they are
+			// kept as long as a policy with an evaluation interval is skipped.
+			if resync && len(duePolicies) == len(flattenedPolicyList) {
+				common.ExpireSecretValues()
 			}
+			evaluatePolicies(duePolicies, concurrency, apiresourcelist, apigroups)
 		}
 
-		// making sure that if processing is > freq we don't sleep
-		// if freq > processing we sleep for the remaining duration
-		elapsed := time.Since(start) / 1000000000 // convert to seconds
-		if float64(freq) > float64(elapsed) {
-			remainingSleep := float64(freq) - float64(elapsed)
-			time.Sleep(time.Duration(remainingSleep) * time.Second)
+		// making sure that if processing is > freq we don't wait,
+		// otherwise wait until the next resync or until the interval of a policy elapses,
+		// evaluating the policies whose objects changed in the meantime
+		wakeUp := nextResync
+		if next, ok := nextEvaluation(flattenedPolicyList); ok && next.Before(wakeUp) && !skipLoop {
+			wakeUp = next
+		}
+		if objWatcher != nil && !skipLoop {
+			handleChangedPolicies(wakeUp, concurrency, apiresourcelist, apigroups)
+		} else {
+			time.Sleep(time.Until(wakeUp))
 		}
 		if KubeClient == nil {
 			return
@@ -244,9 +339,197 @@ func PeriodicallyExecConfigPolicies(freq uint, test bool) {
 	}
 }
 
+// evaluatePolicies evaluates the policies with a pool of concurrency workers and waits for all of them.
+// The same policy must not be passed twice, since its evaluations would then race on its status.
+func evaluatePolicies(policies []*policyv1.ConfigurationPolicy, concurrency uint,
+	apiresourcelist []*metav1.APIResourceList, apigroups []*restmapper.APIGroupResources) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	// the policies evaluated together share the results of their template lookups
+	templates.EnableCache()
+	defer templates.DisableCache()
+	queue := make(chan *policyv1.ConfigurationPolicy)
+	var wg sync.WaitGroup
+	for i := uint(0); i < concurrency && int(i) < len(policies); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for policy := range queue {
+				evaluatePolicy(policy, apiresourcelist, apigroups)
+			}
+		}()
+	}
+	for _, policy := range policies {
+		queue <- policy
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// evaluatePolicy handles the object templates of a policy and records when it was evaluated
+func evaluatePolicy(policy *policyv1.ConfigurationPolicy, apiresourcelist []*metav1.APIResourceList,
+	apigroups []*restmapper.APIGroupResources) {
+	// the policy is shared with the reconciler and the other evaluations, resolving its templates
+	// and updating its status must not modify it
+	start := time.Now()
+	env, err := newControllerEnv()
+	if err != nil {
+		glog.Errorf("Error creating the clients to evaluate policy %s: %v", policy.GetName(), err)
+		return
+	}
+	handleObjectTemplates(*policy.DeepCopy(), env, apiresourcelist, apigroups)
+	evaluationSecondsHistogram.WithLabelValues(policy.GetNamespace(), policy.GetName()).Observe(
+		time.Since(start).Seconds())
+	lastEvaluationsMx.Lock()
+	lastEvaluations[policyKey(policy)] = evaluationRecord{time: time.Now(), generation: policy.GetGeneration()}
+	lastEvaluationsMx.Unlock()
+}
+
+// handleChangedPolicies evaluates the policies queued by the object watcher until the deadline is reached
+func handleChangedPolicies(deadline time.Time, concurrency uint, apiresourcelist []*metav1.APIResourceList,
+	apigroups []*restmapper.APIGroupResources) {
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return
+		case <-objWatcher.notify:
+			duePolicies := []*policyv1.ConfigurationPolicy{}
+			for _, key := range objWatcher.popPending() {
+				policy := getPolicyByKey(key)
+				if policy == nil {
+					// the policy was removed after being queued
+					continue
+				}
+				if !shouldEvaluateChangedPolicy(policy) {
+					// the policy is evaluated once its evaluation interval elapses
+					continue
+				}
+				glog.V(2).Infof("evaluating policy %s after a change to its objects", key)
+				duePolicies = append(duePolicies, policy)
+			}
+			evaluatePolicies(duePolicies, concurrency, apiresourcelist, apigroups)
+		}
+	}
+}
+
+// evaluationRecord holds when a policy was last evaluated, and which generation of it
+type evaluationRecord struct {
+	time       time.Time
+	generation int64
+}
+
+// lastEvaluations holds the last evaluation of each policy key
+var lastEvaluations = map[string]evaluationRecord{}
+
+// lastEvaluationsMx protects lastEvaluations
+var lastEvaluationsMx sync.Mutex
+
+// getEvaluationInterval returns the evaluation interval of the policy in its current compliance state,
+// 0 when it is unset and policyv1.ErrIsNever when the policy must not be evaluated again
+func getEvaluationInterval(policy *policyv1.ConfigurationPolicy) (time.Duration, error) {
+	switch policy.Status.ComplianceState {
+	case policyv1.Compliant:
+		return policy.Spec.EvaluationInterval.GetCompliantInterval()
+	case policyv1.NonCompliant:
+		return policy.Spec.EvaluationInterval.GetNonCompliantInterval()
+	}
+	return 0, nil
+}
+
+// shouldEvaluatePolicy determines whether the policy is due for an evaluation. A policy is always evaluated when
+// its spec changed, otherwise when its evaluation interval elapsed or, when it has none, on a resync.
+func shouldEvaluatePolicy(policy *policyv1.ConfigurationPolicy, resync bool) bool {
+	lastEvaluationsMx.Lock()
+	last, found := lastEvaluations[policyKey(policy)]
+	lastEvaluationsMx.Unlock()
+	if !found || last.generation != policy.GetGeneration() {
+		return true
+	}
+	interval, err := getEvaluationInterval(policy)
+	if err == policyv1.ErrIsNever {
+		glog.V(2).Infof("skipping the evaluation of policy %s, its evaluation interval is never", policy.GetName())
+		return false
+	}
+	if err != nil {
+		glog.Errorf("Invalid evaluation interval in policy %s, evaluating it on every resync: %v",
+			policy.GetName(), err)
+		return resync
+	}
+	if interval == 0 {
+		return resync
+	}
+	return time.Since(last.time) >= interval
+}
+
+// shouldEvaluateChangedPolicy returns true when a policy whose objects changed must be evaluated. The compliant
+// interval doesn't apply, so that a compliant policy reports the drift of its objects right away, unless it is never.
+func shouldEvaluateChangedPolicy(policy *policyv1.ConfigurationPolicy) bool {
+	if policy.Status.ComplianceState == policyv1.Compliant {
+		_, err := policy.Spec.EvaluationInterval.GetCompliantInterval()
+		if err != policyv1.ErrIsNever {
+			return true
+		}
+	}
+	return shouldEvaluatePolicy(policy, true)
+}
+
+// nextEvaluation returns the earliest time a policy with an evaluation interval is due,
+// ok is false when no policy has one
+func nextEvaluation(policies map[string]*policyv1.ConfigurationPolicy) (next time.Time, ok bool) {
+	lastEvaluationsMx.Lock()
+	defer lastEvaluationsMx.Unlock()
+	for _, policy := range policies {
+		last, found := lastEvaluations[policyKey(policy)]
+		if !found {
+			continue
+		}
+		interval, err := getEvaluationInterval(policy)
+		if err != nil || interval == 0 {
+			continue
+		}
+		due := last.time.Add(interval)
+		if !ok || due.Before(next) {
+			next = due
+			ok = true
+		}
+	}
+	return next, ok
+}
+
+// isEnforcing returns true when the remediation action changes the objects, for real or as a dry run
+func isEnforcing(remediation policyv1.RemediationAction) bool {
+	return strings.ToLower(string(remediation)) == strings.ToLower(string(policyv1.Enforce)) || isDryRun(remediation)
+}
+
+// isFieldLevel returns true when the template forbids fields of the object rather than the whole object
+func isFieldLevel(objectT *policyv1.ObjectTemplate) bool {
+	return objectT.FieldLevel &&
+		strings.ToLower(string(objectT.ComplianceType)) == strings.ToLower(string(policyv1.MustNotHave))
+}
+
+// isDryRun returns true when the changes of the remediation action are only reported, not persisted
+func isDryRun(remediation policyv1.RemediationAction) bool {
+	return strings.ToLower(string(remediation)) == strings.ToLower(string(policyv1.DryRun))
+}
+
+// getPolicyByKey returns the available policy with the given "namespace/name" key, or nil if there is none
+func getPolicyByKey(key string) *policyv1.ConfigurationPolicy {
+	for _, policy := range availablePolicies.GetAll() {
+		if policyKey(policy) == key {
+			return policy
+		}
+	}
+	return nil
+}
+
 func addConditionToStatus(plc *policyv1.ConfigurationPolicy, cond *policyv1.Condition, index int,
 	complianceState policyv1.ComplianceState) (updateNeeded bool) {
 	var update bool
+	// the status and the events built from it must not reveal secret values
+	cond.Message = common.Redact(cond.Message)
 	if len((*plc).Status.CompliancyDetails) <= index {
 		(*plc).Status.CompliancyDetails = append((*plc).Status.CompliancyDetails, policyv1.TemplateStatus{
 			ComplianceState: complianceState,
@@ -261,6 +544,8 @@ func addConditionToStatus(plc *policyv1.ConfigurationPolicy, cond *policyv1.Cond
 	if !checkMessageSimilarity((*plc).Status.CompliancyDetails[index].Conditions, cond) {
 		conditions := AppendCondition((*plc).Status.CompliancyDetails[index].Conditions, cond, "", false)
 		(*plc).Status.CompliancyDetails[index].Conditions = conditions
+		(*plc).Status.CompliancyDetails[index].History = appendComplianceHistory(
+			(*plc).Status.CompliancyDetails[index].History, cond, complianceState)
 		update = true
 	}
 	return update
@@ -278,6 +563,17 @@ func createViolation(plc *policyv1.ConfigurationPolicy, index int, reason string
 	return addConditionToStatus(plc, cond, index, policyv1.NonCompliant)
 }
 
+// createTemplateNotResolved reports the object template as unknown when its templates can't be resolved
+func createTemplateNotResolved(plc *policyv1.ConfigurationPolicy, index int, message string) {
+	addConditionToStatus(plc, &policyv1.Condition{
+		Type:               "violation",
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             "Template not resolved",
+		Message:            message,
+	}, index, policyv1.UnknownCompliancy)
+}
+
 func createNotification(plc *policyv1.ConfigurationPolicy, index int, reason string, message string) (result bool) {
 	var cond *policyv1.Condition
 	cond = &policyv1.Condition{
@@ -290,16 +586,115 @@ func createNotification(plc *policyv1.ConfigurationPolicy, index int, reason str
 	return addConditionToStatus(plc, cond, index, policyv1.Compliant)
 }
 
-func handleObjectTemplates(plc policyv1.ConfigurationPolicy, apiresourcelist []*metav1.APIResourceList,
-	apigroups []*restmapper.APIGroupResources) {
-	fmt.Println(fmt.Sprintf("processing object templates for policy %s...", plc.GetName()))
-	plcNamespaces := getPolicyNamespaces(plc)
+// evaluationEnv holds what the evaluation of a policy depends on, so that policies are evaluated the same way
+// by the controller, with its globals, and by the evaluation library, with the clients it is given
+type evaluationEnv struct {
+	dclient        dynamic.Interface
+	listNamespaces common.NamespaceLister
+	// templateClients are the clients the templates of the policy read with
+	templateClients templates.Clients
+	// recorder records the events of the policy, none are recorded when it is nil
+	recorder record.EventRecorder
+	// watcher watches the objects of the policy when it is set
+	watcher *objectWatcher
+	// updateStatus is called with the policy when its status changed
+	updateStatus func(policy *policyv1.ConfigurationPolicy)
+	// recordMetrics sets the compliance metrics of the policy
+	recordMetrics bool
+	// offline is true when the objects are not read from a cluster, the templates can't be resolved and are
+	// reported as unknown
+	offline bool
+}
+
+// getDynamicClient returns the dynamic client of the controller
+func getDynamicClient() (dynamic.Interface, error) {
+	dynamicClientMx.Lock()
+	defer dynamicClientMx.Unlock()
+	if dynamicClient == nil {
+		dclient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			return nil, err
+		}
+		dynamicClient = dclient
+	}
+	return dynamicClient, nil
+}
+
+// newControllerEnv returns the evaluation environment of the controller
+func newControllerEnv() (*evaluationEnv, error) {
+	dclient, err := getDynamicClient()
+	if err != nil {
+		return nil, err
+	}
+	templateClients := templates.Clients{Dynamic: dclient}
+	if KubeClient != nil {
+		templateClients.Kube = *KubeClient
+	}
+	return &evaluationEnv{
+		dclient:         dclient,
+		listNamespaces:  common.GetLabeledNamespaces,
+		templateClients: templateClients,
+		recorder:        recorder,
+		watcher:         objWatcher,
+		updateStatus:    addForUpdate,
+		recordMetrics:   true,
+	}, nil
+}
+
+// EvaluatePolicy evaluates a policy with the given clients instead of the ones of the controller, and returns
+// a copy of the policy with its new status. The status is not updated on the cluster and no event is recorded,
+// but an enforced policy still creates, updates and deletes objects with the dynamic client. The templates read
+// with the dynamic client and with kubeClient, the templates reading Secrets and ConfigMaps fail when it is nil.
+func EvaluatePolicy(plc *policyv1.ConfigurationPolicy, dclient dynamic.Interface, kubeClient kubernetes.Interface,
+	lister common.NamespaceLister, apiresourcelist []*metav1.APIResourceList,
+	apigroups []*restmapper.APIGroupResources) *policyv1.ConfigurationPolicy {
+	return evaluatePolicyWith(plc, &evaluationEnv{
+		dclient:         dclient,
+		listNamespaces:  lister,
+		templateClients: templates.Clients{Kube: kubeClient, Dynamic: dclient},
+	}, apiresourcelist, apigroups)
+}
+
+// evaluatePolicyWith evaluates a policy in the environment and returns a copy of the policy with its new status
+func evaluatePolicyWith(plc *policyv1.ConfigurationPolicy, env *evaluationEnv,
+	apiresourcelist []*metav1.APIResourceList,
+	apigroups []*restmapper.APIGroupResources) *policyv1.ConfigurationPolicy {
+	result := plc.DeepCopy()
+	env.updateStatus = func(policy *policyv1.ConfigurationPolicy) {
+		// the spec of the evaluated policy has its templates resolved
+		result.Status = *policy.Status.DeepCopy()
+	}
+	handleObjectTemplates(*plc.DeepCopy(), env, apiresourcelist, apigroups)
+	result.Status.ComplianceState = getComplianceState(result)
+	result.Status.Severity = result.Spec.Severity
+	return result
+}
+
+func handleObjectTemplates(plc policyv1.ConfigurationPolicy, env *evaluationEnv,
+	apiresourcelist []*metav1.APIResourceList, apigroups []*restmapper.APIGroupResources) {
+	glog.V(3).Infof("processing object templates for policy %s...", plc.GetName())
+	if env.recordMetrics {
+		defer updateComplianceMetrics(&plc)
+	}
 	if plc.Spec.RemediationAction == "" {
 		message := "Policy does not have a RemediationAction specified"
 		update := createViolation(&plc, 0, "No RemediationAction", message)
 		if update {
-			recorder.Event(&plc, eventWarning, fmt.Sprintf(plcFmtStr, plc.GetName()), convertPolicyStatusToString(&plc))
-			addForUpdate(&plc)
+			recordPolicyEvent(env.recorder, &plc, eventWarning, fmt.Sprintf(plcFmtStr, plc.GetName()),
+				convertPolicyStatusToString(&plc))
+			env.updateStatus(&plc)
+		}
+		return
+	}
+	plcNamespaces, nsErr := getPolicyNamespaces(plc, env.listNamespaces)
+	if nsErr != nil {
+		message := fmt.Sprintf("Error selecting the namespaces, please check the namespaceSelector and "+
+			"labelSelector: %v", nsErr)
+		update := createViolation(&plc, 0, "Invalid namespace selector", message)
+		if update {
+			recordPolicyEvent(env.recorder, &plc, eventWarning, fmt.Sprintf(plcFmtStr, plc.GetName()),
+				convertPolicyStatusToString(&plc))
+			env.updateStatus(&plc)
 		}
 		return
 	}
@@ -311,16 +706,69 @@ func handleObjectTemplates(plc policyv1.ConfigurationPolicy, apiresourcelist []*
 	relatedObjects := []policyv1.RelatedObject{}
 	parentUpdate := false
 
-	// initialize apiresources for template processing before starting objectTemplate processing
-	// this is optional but since apiresourcelist is already available,
-	// use this rather than re-discovering the list for generic-lookup
-	templates.SetAPIResources(apiresourcelist)
+	// watch the objects of the object templates so changes to them trigger a new evaluation
+	watchedResources := []watchScope{}
+	if env.watcher != nil {
+		defer func() {
+			env.watcher.watchResources(policyKey(&plc), watchedResources)
+		}()
+	}
+
+	// the templates find the resources of their lookups in the apiresourcelist
+	templateClients := env.templateClients
+	templateClients.APIResources = apiresourcelist
+
+	// the object templates generated by object-templates-raw are already resolved, the "{{" left in them, like in
+	// the values read by its lookups, are not templates
+	templatesResolved := false
+	if plc.Spec.ObjectTemplatesRaw != "" && env.offline {
+		createTemplateNotResolved(&plc, 0, "the object templates are generated by object-templates-raw, which "+
+			"is only resolved on a cluster")
+		env.updateStatus(&plc)
+		return
+	}
+	if plc.Spec.ObjectTemplatesRaw != "" {
+		objectTemplates, err := resolveObjectTemplatesRaw(&plc, templateClients)
+		if err != nil {
+			templateResolutionFailuresCounter.WithLabelValues(plc.GetNamespace(), plc.GetName()).Inc()
+			update := createViolation(&plc, 0, "Error processing template", err.Error())
+			// the error is the only compliancy detail until the object templates are generated again
+			if len(plc.Status.CompliancyDetails) > 1 {
+				plc.Status.CompliancyDetails = plc.Status.CompliancyDetails[:1]
+				update = true
+			}
+			if update {
+				recordPolicyEvent(env.recorder, &plc, eventWarning, fmt.Sprintf(plcFmtStr, plc.GetName()),
+					convertPolicyStatusToString(&plc))
+				env.updateStatus(&plc)
+			}
+			return
+		}
+		// the status of the policy has a compliancy detail for each of the generated object templates
+		plc.Spec.ObjectTemplates = objectTemplates
+		templatesResolved = true
+		if len(plc.Status.CompliancyDetails) > len(objectTemplates) {
+			plc.Status.CompliancyDetails = plc.Status.CompliancyDetails[:len(objectTemplates)]
+			parentUpdate = true
+		}
+	}
 
 	for indx, objectT := range plc.Spec.ObjectTemplates {
 		nonCompliantObjects := map[string]map[string]interface{}{}
 		compliantObjects := map[string]map[string]interface{}{}
-		enforce := strings.ToLower(string(plc.Spec.RemediationAction)) == strings.ToLower(string(policyv1.Enforce))
-		relevantNamespaces := plcNamespaces
+		enforce := isEnforcing(plc.Spec.RemediationAction)
+		relevantNamespaces, nsErr := getObjectTemplateNamespaces(objectT, plcNamespaces, env.listNamespaces)
+		if nsErr != nil {
+			message := fmt.Sprintf("Error selecting the namespaces, please check the namespaceSelector of the "+
+				"object template: %v", nsErr)
+			if createViolation(&plc, indx, "Invalid namespace selector", message) {
+				recordPolicyEvent(env.recorder, &plc, eventWarning, fmt.Sprintf(plcFmtStr, plc.GetName()),
+					convertPolicyStatusToString(&plc))
+				parentUpdate = true
+			}
+			relatedObjects = keepTemplateRelatedObjects(relatedObjects, objectT, apigroups, oldRelated)
+			continue
+		}
 		kind := ""
 		desiredName := ""
 		mustNotHave := strings.ToLower(string(objectT.ComplianceType)) == strings.ToLower(string(policyv1.MustNotHave))
@@ -334,6 +782,10 @@ func handleObjectTemplates(plc policyv1.ConfigurationPolicy, apiresourcelist []*
 			glog.Error(jsonErr)
 			return
 		}
+		// the values of the Secrets of the object definitions are redacted
+		if definition, ok := blob.(map[string]interface{}); ok {
+			common.TrackSecretObject(definition)
+		}
 
 		// Here appears to be a  good place to hook in template processing
 		// This is at the head of objectemplate processing
@@ -343,15 +795,25 @@ func handleObjectTemplates(plc policyv1.ConfigurationPolicy, apiresourcelist []*
 		// and execute  template-processing only if  there is a template pattern "{{" in it
 		// to avoid unnecessary parsing when there is no template in the definition.
 
-		if templates.HasTemplate(string(ext.Raw)) {
-			resolvedblob, tplErr := templates.ResolveTemplate(blob)
+		if !templatesResolved && templates.HasTemplate(string(ext.Raw)) && env.offline {
+			createTemplateNotResolved(&plc, indx, "the object definition has templates, which are only resolved "+
+				"on a cluster")
+			parentUpdate = true
+			continue
+		}
+		if !templatesResolved && templates.HasTemplate(string(ext.Raw)) {
+			resolvedblob, tplErr := templates.ResolvePolicyTemplate(blob, plc.GetNamespace(), templateClients)
 			if tplErr != nil {
-				update := createViolation(&plc, 0, "Error processing template", tplErr.Error())
+				// the error is reported on this template, the other templates are still evaluated
+				templateResolutionFailuresCounter.WithLabelValues(plc.GetNamespace(), plc.GetName()).Inc()
+				update := createViolation(&plc, indx, "Error processing template", tplErr.Error())
 				if update {
-					recorder.Event(&plc, eventWarning, fmt.Sprintf(plcFmtStr, plc.GetName()), convertPolicyStatusToString(&plc))
-					addForUpdate(&plc)
+					recordPolicyEvent(env.recorder, &plc, eventWarning, fmt.Sprintf(plcFmtStr, plc.GetName()),
+						convertPolicyStatusToString(&plc))
+					parentUpdate = true
 				}
-				return
+				relatedObjects = keepTemplateRelatedObjects(relatedObjects, objectT, apigroups, oldRelated)
+				continue
 			}
 
 			//marshal it back and set it on the objectTemplate so be used  in processed further down
@@ -364,25 +826,32 @@ func handleObjectTemplates(plc policyv1.ConfigurationPolicy, apiresourcelist []*
 			//Set the resolved data for use in further processing
 			objectT.ObjectDefinition.Raw = resolveddata
 			blob = resolvedblob
+			if definition, ok := blob.(map[string]interface{}); ok {
+				common.TrackSecretObject(definition)
+			}
 		}
 
 		unstruct.Object = blob.(map[string]interface{})
+		selectedNamespaces := true
 		if md, ok := unstruct.Object["metadata"]; ok {
 			metadata := md.(map[string]interface{})
 			if objectns, ok := metadata["namespace"]; ok {
 				relevantNamespaces = []string{objectns.(string)}
+				selectedNamespaces = false
 			}
 			if objectname, ok := metadata["name"]; ok {
 				desiredName = objectname.(string)
 			}
 		}
+		watchedResources = append(watchedResources, getWatchScopes(apigroups, objectT.ObjectDefinition.Raw,
+			relevantNamespaces, selectedNamespaces, desiredName)...)
 		numCompliant := 0
 		numNonCompliant := 0
 		handled := false
 		objNamespaced := false
 		for _, ns := range relevantNamespaces {
-			names, compliant, reason, objKind, related, update, namespaced := handleObjects(objectT, ns, indx, &plc, config,
-				recorder, apiresourcelist, apigroups)
+			names, compliant, reason, objKind, related, update, namespaced := handleObjects(objectT, ns, indx, &plc, env,
+				apiresourcelist, apigroups)
 			if update {
 				parentUpdate = true
 			}
@@ -423,22 +892,52 @@ func handleObjectTemplates(plc policyv1.ConfigurationPolicy, apiresourcelist []*
 				"kind":        kind,
 				"desiredName": desiredName,
 				"namespaced":  objNamespaced,
+				"fieldLevel":  isFieldLevel(objectT),
 			}
 			statusUpdate := createInformStatus(mustNotHave, numCompliant, numNonCompliant,
-				compliantObjects, nonCompliantObjects, &plc, objData)
+				compliantObjects, nonCompliantObjects, &plc, objData, env.recorder)
 			if statusUpdate {
 				parentUpdate = true
 			}
 		}
 	}
-	checkRelatedAndUpdate(parentUpdate, plc, relatedObjects, oldRelated)
+	relatedObjects = keepObjectProperties(relatedObjects, oldRelated)
+	if plc.Status.Severity != plc.Spec.Severity {
+		parentUpdate = true
+	}
+	checkRelatedAndUpdate(parentUpdate, plc, relatedObjects, oldRelated, env.updateStatus)
+}
+
+// resolveObjectTemplatesRaw resolves the object-templates-raw template of the policy with the clients and decodes
+// the resulting YAML list of object templates
+func resolveObjectTemplatesRaw(plc *policyv1.ConfigurationPolicy,
+	clients templates.Clients) ([]*policyv1.ObjectTemplate, error) {
+	resolved, err := templates.ResolveRawTemplate(plc.Spec.ObjectTemplatesRaw, plc.GetNamespace(), clients)
+	if err != nil {
+		return nil, err
+	}
+	objectTemplates := []*policyv1.ObjectTemplate{}
+	if err := yaml.Unmarshal([]byte(resolved), &objectTemplates); err != nil {
+		return nil, fmt.Errorf("the resolved object-templates-raw is not a list of object templates: %v", err)
+	}
+	for i, objectT := range objectTemplates {
+		if objectT == nil {
+			return nil, fmt.Errorf("the resolved object-templates-raw has an empty object template at index %v", i)
+		}
+		// the values of the generated Secrets are redacted
+		definition := map[string]interface{}{}
+		if err := json.Unmarshal(objectT.ObjectDefinition.Raw, &definition); err == nil {
+			common.TrackSecretObject(definition)
+		}
+	}
+	return objectTemplates, nil
 }
 
 func checkRelatedAndUpdate(update bool, plc policyv1.ConfigurationPolicy, related,
-	oldRelated []policyv1.RelatedObject) {
+	oldRelated []policyv1.RelatedObject, updateStatus func(policy *policyv1.ConfigurationPolicy)) {
 	sortUpdate := sortRelatedObjectsAndUpdate(&plc, related, oldRelated)
 	if update || sortUpdate {
-		addForUpdate(&plc)
+		updateStatus(&plc)
 	}
 }
 
@@ -471,18 +970,27 @@ func sortRelatedObjectsAndUpdate(plc *policyv1.ConfigurationPolicy, related,
 
 func createInformStatus(mustNotHave bool, numCompliant int, numNonCompliant int,
 	compliantObjects map[string]map[string]interface{}, nonCompliantObjects map[string]map[string]interface{},
-	plc *policyv1.ConfigurationPolicy, objData map[string]interface{}) (updateNeeded bool) {
+	plc *policyv1.ConfigurationPolicy, objData map[string]interface{}, rec record.EventRecorder) (updateNeeded bool) {
 	update := false
 	compliant := false
 	desiredName := objData["desiredName"].(string)
 	indx := objData["indx"].(int)
 	kind := objData["kind"].(string)
 	namespaced := objData["namespaced"].(bool)
+	fieldLevel, _ := objData["fieldLevel"].(bool)
 	if kind == "" {
 		return
 	}
 
-	if mustNotHave {
+	if mustNotHave && fieldLevel {
+		// the objects found are the ones with forbidden fields
+		compliant = numNonCompliant == 0
+		if compliant {
+			update = createMustNotHaveFieldsStatus(kind, compliantObjects, namespaced, plc, indx, compliant)
+		} else {
+			update = createMustNotHaveFieldsStatus(kind, nonCompliantObjects, namespaced, plc, indx, compliant)
+		}
+	} else if mustNotHave {
 		if numNonCompliant > 0 { // We want no resources, but some were found
 			//noncompliant; mustnothave and objects exist
 			update = createMustNotHaveStatus(kind, nonCompliantObjects, namespaced, plc, indx, compliant)
@@ -512,26 +1020,25 @@ func createInformStatus(mustNotHave bool, numCompliant int, numNonCompliant int,
 		if !compliant {
 			eventType = eventWarning
 		}
-		if recorder != nil {
-			recorder.Event(plc, eventType, fmt.Sprintf(plcFmtStr, plc.GetName()), convertPolicyStatusToString(plc))
-		}
+		recordPolicyEvent(rec, plc, eventType, fmt.Sprintf(plcFmtStr, plc.GetName()),
+			convertPolicyStatusToString(plc))
 	}
 	return update
 }
 
 func handleObjects(objectT *policyv1.ObjectTemplate, namespace string, index int, policy *policyv1.ConfigurationPolicy,
-	config *rest.Config, recorder record.EventRecorder, apiresourcelist []*metav1.APIResourceList,
+	env *evaluationEnv, apiresourcelist []*metav1.APIResourceList,
 	apigroups []*restmapper.APIGroupResources) (objNameList []string, compliant bool, reason string,
 	rsrcKind string, relatedObjects []policyv1.RelatedObject, pUpdate bool, isNamespaced bool) {
 	if namespace != "" {
-		fmt.Println(fmt.Sprintf("handling object template [%d] in namespace %s", index, namespace))
+		glog.V(3).Infof("handling object template [%d] in namespace %s", index, namespace)
 	} else {
-		fmt.Println(fmt.Sprintf("handling object template [%d] (no namespace specified)", index))
+		glog.V(3).Infof("handling object template [%d] (no namespace specified)", index)
 	}
 	namespaced := true
 	needUpdate := false
 	ext := objectT.ObjectDefinition
-	mapping, mappingUpdate := getMapping(apigroups, ext, policy, index)
+	mapping, mappingUpdate := getMapping(apigroups, ext, policy, index, env.recorder)
 	if mapping == nil {
 		return nil, false, "", "", nil, (needUpdate || mappingUpdate), namespaced
 	}
@@ -544,12 +1051,15 @@ func handleObjects(objectT *policyv1.ObjectTemplate, namespace string, index int
 	unstruct.Object = blob.(map[string]interface{}) //set object to the content of the blob after Unmarshalling
 	exists := true
 	objNames := []string{}
+	// the objects listed for a template without a name
+	var listedObjects []unstructured.Unstructured
 	remediation := policy.Spec.RemediationAction
 	name, kind, metaNamespace := getDetails(unstruct)
 	if metaNamespace != "" {
 		namespace = metaNamespace
 	}
-	dclient, rsrc, namespaced := getClientRsrc(mapping, apiresourcelist)
+	dclient := env.dclient
+	rsrc, namespaced := getRsrc(mapping, apiresourcelist)
 	if namespaced && namespace == "" {
 		//namespaced but none specified, generate violation
 		updateStatus := createViolation(policy, index, "K8s missing namespace",
@@ -560,7 +1070,7 @@ func handleObjects(objectT *policyv1.ObjectTemplate, namespace string, index int
 				policy.Status.CompliancyDetails[index].ComplianceState == policyv1.NonCompliant {
 				eventType = eventWarning
 			}
-			recorder.Event(policy, eventType, fmt.Sprintf(eventFmtStr, policy.GetName(), name),
+			recordPolicyEvent(env.recorder, policy, eventType, fmt.Sprintf(eventFmtStr, policy.GetName(), name),
 				convertPolicyStatusToString(policy))
 			needUpdate = true
 		}
@@ -570,8 +1080,24 @@ func handleObjects(objectT *policyv1.ObjectTemplate, namespace string, index int
 		exists = objectExists(namespaced, namespace, name, rsrc, unstruct, dclient)
 		objNames = append(objNames, name)
 	} else if kind != "" {
-		objNames = append(objNames, getNamesOfKind(unstruct, rsrc, namespaced,
-			namespace, dclient, strings.ToLower(string(objectT.ComplianceType)))...)
+		// an invalid field selector is only rejected by the API server when listing the objects
+		listOpts, err := getObjectListOptions(objectT.ObjectSelector)
+		var kindNames []string
+		if err == nil {
+			kindNames, listedObjects, err = getNamesOfKind(unstruct, rsrc, namespaced, namespace, dclient,
+				strings.ToLower(string(objectT.ComplianceType)), isFieldLevel(objectT), objectT.ObjectSelector,
+				listOpts)
+		}
+		if err != nil {
+			message := fmt.Sprintf("Error selecting the objects, please check the objectSelector: %v", err)
+			if createViolation(policy, index, "Invalid object selector", message) {
+				recordPolicyEvent(env.recorder, policy, eventWarning, fmt.Sprintf(plcFmtStr, policy.GetName()),
+					convertPolicyStatusToString(policy))
+				needUpdate = true
+			}
+			return nil, false, "", "", nil, needUpdate, namespaced
+		}
+		objNames = append(objNames, kindNames...)
 		remediation = "inform"
 		if len(objNames) == 0 {
 			exists = false
@@ -582,21 +1108,39 @@ func handleObjects(objectT *policyv1.ObjectTemplate, namespace string, index int
 	reason = ""
 	// if the compliance is calculated by the handleSingleObj function, do not override the setting
 	complianceCalculated := false
+	var details policyv1.RelatedObject
+	// the assertions are checked on the objects found for the template
+	checkAssertions := exists && objShouldExist && !isFieldLevel(objectT) && len(objectT.Assertions) > 0
 	if len(objNames) == 1 {
 		name = objNames[0]
-		objNames, compliant, rsrcKind, needUpdate = handleSingleObj(policy, remediation, exists, objShouldExist, rsrc,
-			dclient, objectT, map[string]interface{}{
-				"name":       name,
-				"namespace":  namespace,
-				"namespaced": namespaced,
-				"index":      index,
-				"unstruct":   unstruct,
-			})
+		data := map[string]interface{}{
+			"name":       name,
+			"namespace":  namespace,
+			"namespaced": namespaced,
+			"index":      index,
+			"unstruct":   unstruct,
+		}
+		if checkAssertions && listedObjects != nil {
+			// the listed object is not updated, its assertions are checked without getting it again
+			data["assertionFailures"] = getAssertionFailures(objectT, objNames, listedObjects)[name]
+		}
+		objNames, compliant, rsrcKind, needUpdate, details = handleSingleObj(policy, env.recorder, remediation, exists,
+			objShouldExist, rsrc, dclient, objectT, data)
 		complianceCalculated = true
 	}
 
-	if complianceCalculated {
+	if exists && isFieldLevel(objectT) {
+		// the object is expected to exist, only its forbidden fields must not
+		rsrcKind = rsrc.Resource
+		reason = reasonFieldsFound
+		if compliant {
+			reason = reasonFieldsNotFound
+		}
+	} else if complianceCalculated {
 		reason = generateSingleObjReason(objShouldExist, compliant, exists)
+		if len(details.FailedAssertions) > 0 {
+			reason = reasonAssertionsFailed
+		}
 	} else {
 		if !exists && objShouldExist {
 			compliant = false
@@ -620,6 +1164,36 @@ func handleObjects(objectT *policyv1.ObjectTemplate, namespace string, index int
 	if complianceCalculated {
 		// enforce could clear the objNames array so use name instead
 		relatedObjects = addRelatedObjects(policy, compliant, rsrc, namespace, namespaced, []string{name}, reason)
+		if len(relatedObjects) == 1 {
+			// report why the object does not match
+			if !compliant {
+				relatedObjects[0].Differences = details.Differences
+				relatedObjects[0].FailedAssertions = details.FailedAssertions
+			}
+			relatedObjects[0].Properties = details.Properties
+		}
+	} else if checkAssertions && len(objNames) > 0 {
+		// the objects failing the assertions are noncompliant, the other objects stay compliant
+		assertionFailures := getAssertionFailures(objectT, objNames, listedObjects)
+		passing := []string{}
+		failing := []string{}
+		for _, objName := range objNames {
+			if _, failed := assertionFailures[objName]; failed {
+				failing = append(failing, objName)
+			} else {
+				passing = append(passing, objName)
+			}
+		}
+		relatedObjects = addRelatedObjects(policy, true, rsrc, namespace, namespaced, passing, reason)
+		if len(failing) > 0 {
+			objNames = failing
+			compliant = false
+			reason = reasonAssertionsFailed
+			for _, related := range addRelatedObjects(policy, false, rsrc, namespace, namespaced, failing, reason) {
+				related.FailedAssertions = assertionFailures[related.Object.Metadata.Name]
+				relatedObjects = append(relatedObjects, related)
+			}
+		}
 	} else {
 		relatedObjects = addRelatedObjects(policy, compliant, rsrc, namespace, namespaced, objNames, reason)
 	}
@@ -642,9 +1216,11 @@ func generateSingleObjReason(objShouldExist bool, compliant bool, exists bool) (
 	return reason
 }
 
-func handleSingleObj(policy *policyv1.ConfigurationPolicy, remediation policyv1.RemediationAction, exists bool,
-	objShouldExist bool, rsrc schema.GroupVersionResource, dclient dynamic.Interface, objectT *policyv1.ObjectTemplate,
-	data map[string]interface{}) (objNameList []string, compliance bool, rsrcKind string, shouldUpdate bool) {
+func handleSingleObj(policy *policyv1.ConfigurationPolicy, rec record.EventRecorder,
+	remediation policyv1.RemediationAction, exists bool, objShouldExist bool, rsrc schema.GroupVersionResource,
+	dclient dynamic.Interface, objectT *policyv1.ObjectTemplate,
+	data map[string]interface{}) (objNameList []string, compliance bool, rsrcKind string, shouldUpdate bool,
+	details policyv1.RelatedObject) {
 	var err error
 	var compliant bool
 	updateNeeded := false
@@ -662,8 +1238,13 @@ func handleSingleObj(policy *policyv1.ConfigurationPolicy, remediation policyv1.
 	unstruct := data["unstruct"].(unstructured.Unstructured)
 	if !exists && objShouldExist {
 		//it is a musthave and it does not exist, so it must be created
-		if strings.ToLower(string(remediation)) == strings.ToLower(string(policyv1.Enforce)) {
-			updateNeeded, err = handleMissingMustHave(policy, remediation, rsrc, dclient, data)
+		if isEnforcing(remediation) {
+			var createdUID string
+			updateNeeded, createdUID, err = handleMissingMustHave(policy, remediation, rsrc, dclient, data)
+			if createdUID != "" && !isDryRun(remediation) {
+				// track the object so it can be pruned with the policy, a dry run create returns a UID too
+				details.Properties = &policyv1.ObjectProperties{CreatedByPolicy: true, UID: createdUID}
+			}
 			if err != nil {
 				// violation created for handling error
 				glog.Errorf("error handling a missing object `%v` that is a must have according to policy `%v`", name, policy.Name)
@@ -672,9 +1253,18 @@ func handleSingleObj(policy *policyv1.ConfigurationPolicy, remediation policyv1.
 			compliant = false
 		}
 	}
-	if exists && !objShouldExist {
+	fieldLevel := isFieldLevel(objectT)
+	if exists && fieldLevel {
+		//it is a field-level mustnothave and it exists, so its forbidden fields must be removed
+		updateNeeded, compliant, details.Differences, err = handleExistsMustNotHaveFields(policy, remediation, rsrc,
+			dclient, data)
+		if err != nil {
+			glog.Errorf("error handling the forbidden fields of object `%v` according to policy `%v`",
+				name, policy.Name)
+		}
+	} else if exists && !objShouldExist {
 		//it is a mustnothave but it exist, so it must be deleted
-		if strings.ToLower(string(remediation)) == strings.ToLower(string(policyv1.Enforce)) {
+		if isEnforcing(remediation) {
 			updateNeeded, err = handleExistsMustNotHave(policy, remediation, rsrc, dclient, data)
 			if err != nil {
 				glog.Errorf("error handling a existing object `%v` that is a must NOT have according to policy `%v`",
@@ -687,7 +1277,7 @@ func handleSingleObj(policy *policyv1.ConfigurationPolicy, remediation policyv1.
 	if !exists && !objShouldExist {
 		//it is a must not have and it does not exist, so it is compliant
 		compliant = true
-		if strings.ToLower(string(remediation)) == strings.ToLower(string(policyv1.Enforce)) {
+		if isEnforcing(remediation) {
 			glog.V(7).Infof("entering `does not exists` & ` must not have`")
 			updateNeeded = createMustNotHaveStatus(rsrc.Resource, compliantObject, namespaced, policy, index, compliant)
 		}
@@ -696,21 +1286,44 @@ func handleSingleObj(policy *policyv1.ConfigurationPolicy, remediation policyv1.
 	processingErr := false
 	specViolation := false
 
-	if exists {
-		updated, throwSpecViolation, msg, pErr := updateTemplate(
+	if exists && !fieldLevel {
+		var updated, throwSpecViolation bool
+		var msg string
+		var pErr bool
+		updated, throwSpecViolation, msg, pErr, details.Differences = updateTemplate(
 			strings.ToLower(string(objectT.ComplianceType)),
-			data, remediation, rsrc, dclient, unstruct.Object["kind"].(string), nil)
+			data, remediation, rsrc, dclient, unstruct.Object["kind"].(string), nil, objectT.ForceOwnership)
 		if !updated && throwSpecViolation {
 			specViolation = throwSpecViolation
 			compliant = false
+		} else if updated && msg != "" {
+			// dry run, report the changes the update would make
+			nameStr := createResourceNameStr([]string{name}, namespace, namespaced)
+			message := fmt.Sprintf("%v %v does not match, and would be updated (dry run), changed fields: %v",
+				rsrc.Resource, nameStr, msg)
+			updateNeeded = createViolation(policy, data["index"].(int), "K8s dry run update", message)
 		} else if !updated && msg != "" {
 			updateNeeded = createViolation(policy, data["index"].(int), "K8s update template error", msg)
 		} else if objShouldExist {
-			//it is a must have and it does exist, so it is compliant
-			compliant = true
-			if strings.ToLower(string(remediation)) == strings.ToLower(string(policyv1.Enforce)) {
-				glog.V(7).Infof("entering `exists` & ` must have`")
-				updateNeeded = createMustHaveStatus("", rsrc.Resource, compliantObject, namespaced, policy, index, compliant)
+			// the assertions are checked on the updated object
+			details.FailedAssertions = getSingleObjAssertionFailures(objectT, rsrc, dclient, data)
+			if len(details.FailedAssertions) > 0 {
+				// the assertions can not be enforced, the object is reported as noncompliant
+				compliant = false
+				if isEnforcing(remediation) {
+					nameStr := createResourceNameStr([]string{name}, namespace, namespaced)
+					message := fmt.Sprintf("%v %v found but failed the assertions: %v", rsrc.Resource, nameStr,
+						describeAssertionFailures(details.FailedAssertions))
+					updateNeeded = createViolation(policy, index, "K8s assertions failed", message)
+				}
+			} else {
+				//it is a must have and it does exist, so it is compliant
+				compliant = true
+				if isEnforcing(remediation) {
+					glog.V(7).Infof("entering `exists` & ` must have`")
+					updateNeeded = createMustHaveStatus("", rsrc.Resource, compliantObject, namespaced, policy, index,
+						compliant)
+				}
 			}
 		}
 		processingErr = pErr
@@ -723,35 +1336,26 @@ func handleSingleObj(policy *policyv1.ConfigurationPolicy, remediation policyv1.
 			eventType = eventWarning
 			compliant = false
 		}
-		recorder.Event(policy, eventType, fmt.Sprintf(eventFmtStr, policy.GetName(), name),
+		recordPolicyEvent(rec, policy, eventType, fmt.Sprintf(eventFmtStr, policy.GetName(), name),
 			convertPolicyStatusToString(policy))
-		return nil, compliant, "", updateNeeded
+		return nil, compliant, "", updateNeeded, details
 	}
 
 	if processingErr {
-		return nil, false, "", updateNeeded
+		return nil, false, "", updateNeeded, details
 	}
 
 	if strings.ToLower(string(remediation)) == strings.ToLower(string(policyv1.Inform)) || specViolation {
-		return []string{name}, compliant, rsrc.Resource, updateNeeded
+		return []string{name}, compliant, rsrc.Resource, updateNeeded, details
 	}
 
-	return nil, compliant, "", false
+	return nil, compliant, "", false, details
 }
 
-func getClientRsrc(mapping *meta.RESTMapping, apiresourcelist []*metav1.APIResourceList) (dclient dynamic.Interface,
-	rsrc schema.GroupVersionResource, namespaced bool) {
+// getRsrc returns the resource of the mapping and whether it is namespaced
+func getRsrc(mapping *meta.RESTMapping, apiresourcelist []*metav1.APIResourceList) (rsrc schema.GroupVersionResource,
+	namespaced bool) {
 	namespaced = false
-	restconfig := config
-	restconfig.GroupVersion = &schema.GroupVersion{
-		Group:   mapping.GroupVersionKind.Group,
-		Version: mapping.GroupVersionKind.Version,
-	}
-	dclient, err := dynamic.NewForConfig(restconfig)
-	if err != nil {
-		glog.Fatal(err)
-	}
-
 	rsrc = mapping.Resource
 	for _, apiresourcegroup := range apiresourcelist {
 		if apiresourcegroup.GroupVersion == join(mapping.GroupVersionKind.Group, "/", mapping.GroupVersionKind.Version) {
@@ -764,19 +1368,19 @@ func getClientRsrc(mapping *meta.RESTMapping, apiresourcelist []*metav1.APIResou
 			}
 		}
 	}
-	return dclient, rsrc, namespaced
+	return rsrc, namespaced
 }
 
 func getMapping(apigroups []*restmapper.APIGroupResources, ext runtime.RawExtension,
-	policy *policyv1.ConfigurationPolicy, index int) (mapping *meta.RESTMapping, update bool) {
+	policy *policyv1.ConfigurationPolicy, index int, rec record.EventRecorder) (mapping *meta.RESTMapping, update bool) {
 	updateNeeded := false
 	restmapper := restmapper.NewDiscoveryRESTMapper(apigroups)
-	glog.V(9).Infof("reading raw object: %v", string(ext.Raw))
+	glog.V(9).Infof("reading raw object: %v", common.Redact(string(ext.Raw)))
 	_, gvk, err := unstructured.UnstructuredJSONScheme.Decode(ext.Raw, nil, nil)
 	if err != nil {
-		decodeErr := fmt.Sprintf("Decoding error, please check your policy file!"+
+		decodeErr := common.Redact(fmt.Sprintf("Decoding error, please check your policy file!"+
 			" Aborting handling the object template at index [%v] in policy `%v` with error = `%v`",
-			index, policy.Name, err)
+			index, policy.Name, err))
 		glog.Errorf(decodeErr)
 
 		if len(policy.Status.CompliancyDetails) <= index {
@@ -786,15 +1390,18 @@ func getMapping(apigroups []*restmapper.APIGroupResources, ext runtime.RawExtens
 			})
 		}
 		policy.Status.CompliancyDetails[index].ComplianceState = policyv1.NonCompliant
-		policy.Status.CompliancyDetails[index].Conditions = []policyv1.Condition{
-			policyv1.Condition{
-				Type:               "violation",
-				Status:             corev1.ConditionTrue,
-				LastTransitionTime: metav1.Now(),
-				Reason:             "K8s decode object definition error",
-				Message:            decodeErr,
-			},
-		}
+		cond := policyv1.Condition{
+			Type:               "violation",
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.Now(),
+			Reason:             "K8s decode object definition error",
+			Message:            decodeErr,
+		}
+		if !checkMessageSimilarity(policy.Status.CompliancyDetails[index].Conditions, &cond) {
+			policy.Status.CompliancyDetails[index].History = appendComplianceHistory(
+				policy.Status.CompliancyDetails[index].History, &cond, policyv1.NonCompliant)
+		}
+		policy.Status.CompliancyDetails[index].Conditions = []policyv1.Condition{cond}
 		return nil, true
 	}
 	mapping, err = restmapper.RESTMapping(gvk.GroupKind(), gvk.Version)
@@ -834,11 +1441,13 @@ func getMapping(apigroups []*restmapper.APIGroupResources, ext runtime.RawExtens
 			if !checkMessageSimilarity(policy.Status.CompliancyDetails[index].Conditions, cond) {
 				conditions := AppendCondition(policy.Status.CompliancyDetails[index].Conditions, cond, gvk.GroupKind().Kind, false)
 				policy.Status.CompliancyDetails[index].Conditions = conditions
+				policy.Status.CompliancyDetails[index].History = appendComplianceHistory(
+					policy.Status.CompliancyDetails[index].History, cond, policyv1.NonCompliant)
 				updateNeeded = true
 			}
 		}
 		if updateNeeded {
-			recorder.Event(policy, eventWarning, fmt.Sprintf(plcFmtStr, policy.GetName()), errMsg)
+			recordPolicyEvent(rec, policy, eventWarning, fmt.Sprintf(plcFmtStr, policy.GetName()), errMsg)
 		}
 		return nil, updateNeeded
 	}
@@ -868,10 +1477,17 @@ func getDetails(unstruct unstructured.Unstructured) (name string, kind string, n
 	return name, kind, namespace
 }
 
-func buildNameList(unstruct unstructured.Unstructured, complianceType string,
+func buildNameList(unstruct unstructured.Unstructured, complianceType string, fieldLevel bool,
 	resList *unstructured.UnstructuredList) (kindNameList []string) {
 	for i := range resList.Items {
 		uObj := resList.Items[i]
+		if fieldLevel {
+			// list the objects with forbidden fields
+			if _, differences := removeForbiddenFields(unstruct, &uObj, false); len(differences) > 0 {
+				kindNameList = append(kindNameList, uObj.GetName())
+			}
+			continue
+		}
 		match := true
 		for key := range unstruct.Object {
 			errorMsg, updateNeeded, _, skipped := handleSingleKey(key, unstruct, &uObj, complianceType)
@@ -889,25 +1505,32 @@ func buildNameList(unstruct unstructured.Unstructured, complianceType string,
 }
 
 // getNamesOfKind returns an array with names of all of the resources found
-// matching the GVK specified.
+// matching the GVK specified, and the objectSelector of the template, with the selected objects, or the error
+// listing them
 func getNamesOfKind(unstruct unstructured.Unstructured, rsrc schema.GroupVersionResource,
-	namespaced bool, ns string, dclient dynamic.Interface, complianceType string) (kindNameList []string) {
+	namespaced bool, ns string, dclient dynamic.Interface, complianceType string,
+	fieldLevel bool, selector *policyv1.ObjectSelector,
+	listOpts metav1.ListOptions) ([]string, []unstructured.Unstructured, error) {
+	var res dynamic.ResourceInterface = dclient.Resource(rsrc)
 	if namespaced {
-		res := dclient.Resource(rsrc).Namespace(ns)
-		resList, err := res.List(context.TODO(), metav1.ListOptions{})
-		if err != nil {
-			glog.Error(err)
-			return kindNameList
-		}
-		return buildNameList(unstruct, complianceType, resList)
+		res = dclient.Resource(rsrc).Namespace(ns)
 	}
-	res := dclient.Resource(rsrc)
-	resList, err := res.List(context.TODO(), metav1.ListOptions{})
+	resList, err := res.List(context.TODO(), listOpts)
 	if err != nil {
 		glog.Error(err)
-		return kindNameList
+		return nil, nil, err
+	}
+	if selector != nil && selector.Owner != nil {
+		// the API server can not select the objects by owner
+		owned := []unstructured.Unstructured{}
+		for _, object := range resList.Items {
+			if matchesOwner(object, selector) {
+				owned = append(owned, object)
+			}
+		}
+		resList.Items = owned
 	}
-	return buildNameList(unstruct, complianceType, resList)
+	return buildNameList(unstruct, complianceType, fieldLevel, resList), resList.Items, nil
 }
 
 func handleExistsMustNotHave(plc *policyv1.ConfigurationPolicy, action policyv1.RemediationAction,
@@ -923,11 +1546,15 @@ func handleExistsMustNotHave(plc *policyv1.ConfigurationPolicy, action policyv1.
 	var update, deleted bool
 	var err error
 
-	if strings.ToLower(string(action)) == strings.ToLower(string(policyv1.Enforce)) {
+	if isEnforcing(action) {
 		nameStr := createResourceNameStr([]string{name}, namespace, namespaced)
-		if deleted, err = deleteObject(namespaced, namespace, name, rsrc, dclient); !deleted {
+		dryRun := isDryRun(action)
+		if deleted, err = deleteObject(namespaced, namespace, name, rsrc, dclient, dryRun); !deleted {
 			message := fmt.Sprintf("%v %v exists, and cannot be deleted, reason: `%v`", rsrc.Resource, nameStr, err)
 			update = createViolation(plc, index, "K8s deletion error", message)
+		} else if dryRun {
+			message := fmt.Sprintf("%v %v exists, and would be deleted (dry run)", rsrc.Resource, nameStr)
+			update = createViolation(plc, index, "K8s dry run deletion", message)
 		} else { //deleted successfully
 			message := fmt.Sprintf("%v %v existed, and was deleted successfully", rsrc.Resource, nameStr)
 			update = createNotification(plc, index, "K8s deletion success", message)
@@ -936,9 +1563,76 @@ func handleExistsMustNotHave(plc *policyv1.ConfigurationPolicy, action policyv1.
 	return update, err
 }
 
+// handleExistsMustNotHaveFields checks an existing object against a field-level mustnothave template,
+// and removes the forbidden fields from it when enforcing
+func handleExistsMustNotHaveFields(plc *policyv1.ConfigurationPolicy, action policyv1.RemediationAction,
+	rsrc schema.GroupVersionResource, dclient dynamic.Interface,
+	metadata map[string]interface{}) (result bool, compliant bool, differences []policyv1.FieldDifference,
+	erro error) {
+	glog.V(7).Infof("entering `exists` & ` must not have fields`")
+
+	name := metadata["name"].(string)
+	namespace := metadata["namespace"].(string)
+	index := metadata["index"].(int)
+	namespaced := metadata["namespaced"].(bool)
+	unstruct := metadata["unstruct"].(unstructured.Unstructured)
+
+	var res dynamic.ResourceInterface
+	if namespaced {
+		res = dclient.Resource(rsrc).Namespace(namespace)
+	} else {
+		res = dclient.Resource(rsrc)
+	}
+	existingObj, err := res.Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		glog.Errorf(getObjError, name)
+		return false, false, nil, err
+	}
+	// the values of a secret are not reported
+	remaining, differences := removeForbiddenFields(unstruct, existingObj, unstruct.GetKind() == "Secret")
+	var update bool
+	if len(differences) == 0 {
+		if isEnforcing(action) {
+			compliantObject := map[string]map[string]interface{}{
+				namespace: {"names": []string{name}},
+			}
+			update = createMustNotHaveFieldsStatus(rsrc.Resource, compliantObject, namespaced, plc, index, true)
+		}
+		return update, true, nil, nil
+	}
+	if !isEnforcing(action) {
+		return false, false, differences, nil
+	}
+	nameStr := createResourceNameStr([]string{name}, namespace, namespaced)
+	dryRun := isDryRun(action)
+	// the forbidden fields may be owned by other field managers, so the whole object is replaced
+	updateOptions := metav1.UpdateOptions{FieldManager: fieldManager}
+	if dryRun {
+		updateOptions.DryRun = []string{metav1.DryRunAll}
+	}
+	_, err = res.Update(context.TODO(), remaining, updateOptions)
+	recordEnforcement(actionUpdate, dryRun, err)
+	if err != nil {
+		message := fmt.Sprintf("%v %v has forbidden fields, and they cannot be removed, reason: `%v`",
+			rsrc.Resource, nameStr, err)
+		update = createViolation(plc, index, "K8s update template error", message)
+		return update, false, differences, err
+	}
+	if dryRun {
+		message := fmt.Sprintf("%v %v has forbidden fields, and they would be removed (dry run), changed fields: %v",
+			rsrc.Resource, nameStr, formatFieldDifferences(differences))
+		update = createViolation(plc, index, "K8s dry run update", message)
+		return update, false, differences, nil
+	}
+	message := fmt.Sprintf("%v %v had forbidden fields, and they were removed successfully, changed fields: %v",
+		rsrc.Resource, nameStr, formatFieldDifferences(differences))
+	update = createNotification(plc, index, "K8s update success", message)
+	return update, true, differences, nil
+}
+
 func handleMissingMustHave(plc *policyv1.ConfigurationPolicy, action policyv1.RemediationAction,
 	rsrc schema.GroupVersionResource, dclient dynamic.Interface,
-	metadata map[string]interface{}) (result bool, erro error) {
+	metadata map[string]interface{}) (result bool, createdUID string, erro error) {
 	glog.V(7).Infof("entering `does not exists` & ` must have`")
 
 	name := metadata["name"].(string)
@@ -949,65 +1643,53 @@ func handleMissingMustHave(plc *policyv1.ConfigurationPolicy, action policyv1.Re
 
 	var update, created bool
 	var err error
-	if strings.ToLower(string(action)) == strings.ToLower(string(policyv1.Enforce)) {
+	if isEnforcing(action) {
 		nameStr := createResourceNameStr([]string{name}, namespace, namespaced)
-		if created, err = createObject(namespaced, namespace, name, rsrc, unstruct, dclient); !created {
+		dryRun := isDryRun(action)
+		if created, createdUID, err = createObject(namespaced, namespace, name, rsrc, unstruct, dclient,
+			dryRun); !created {
 			message := fmt.Sprintf("%v %v is missing, and cannot be created, reason: `%v`", rsrc.Resource, nameStr, err)
 			update = createViolation(plc, index, "K8s creation error", message)
+		} else if dryRun {
+			message := fmt.Sprintf("%v %v is missing, and would be created (dry run)", rsrc.Resource, nameStr)
+			update = createViolation(plc, index, "K8s dry run creation", message)
 		} else { //created successfully
 			glog.V(8).Infof("entering [%v] created successfully", name)
 			message := fmt.Sprintf("%v %v was missing, and was created successfully", rsrc.Resource, nameStr)
 			update = createNotification(plc, index, "K8s creation success", message)
 		}
 	}
-	return update, err
+	return update, createdUID, err
 }
 
-func getPolicyNamespaces(policy policyv1.ConfigurationPolicy) []string {
-	//get all namespaces
-	allNamespaces := getAllNamespaces()
-	//then get the list of included
-	includedNamespaces := []string{}
-	included := policy.Spec.NamespaceSelector.Include
-	for _, value := range included {
-		found := common.FindPattern(value, allNamespaces)
-		if found != nil {
-			includedNamespaces = append(includedNamespaces, found...)
-		}
-
-	}
-	//then get the list of excluded
-	excludedNamespaces := []string{}
-	excluded := policy.Spec.NamespaceSelector.Exclude
-	for _, value := range excluded {
-		found := common.FindPattern(value, allNamespaces)
-		if found != nil {
-			excludedNamespaces = append(excludedNamespaces, found...)
-		}
-
+func getPolicyNamespaces(policy policyv1.ConfigurationPolicy, lister common.NamespaceLister) ([]string, error) {
+	//get the namespaces picked by the namespace selector and labels
+	finalList, err := common.SelectNamespacesWith(lister, policy.Spec.NamespaceSelector, policy.Spec.LabelSelector)
+	if err != nil {
+		glog.Errorf("Error selecting the namespaces of policy %s: %v", policy.GetName(), err)
+		return []string{""}, err
 	}
-
-	//then get the list of deduplicated
-	finalList := common.DeduplicateItems(includedNamespaces, excludedNamespaces)
 	if len(finalList) == 0 {
 		finalList = append(finalList, "")
 	}
-	return finalList
+	return finalList, nil
 }
 
-func getAllNamespaces() (list []string) {
-	listOpt := &metav1.ListOptions{}
-
-	nsList, err := (*KubeClient).CoreV1().Namespaces().List(context.TODO(), *listOpt)
+// getObjectTemplateNamespaces returns the namespaces selected by the namespaceSelector of the object template, or
+// the namespaces of the policy when the template has none
+func getObjectTemplateNamespaces(objectT *policyv1.ObjectTemplate, plcNamespaces []string,
+	lister common.NamespaceLister) ([]string, error) {
+	if objectT.NamespaceSelector == nil {
+		return plcNamespaces, nil
+	}
+	finalList, err := common.SelectNamespacesWith(lister, *objectT.NamespaceSelector, nil)
 	if err != nil {
-		glog.Errorf("Error fetching namespaces from the API server: %v", err)
+		return []string{""}, err
 	}
-	namespacesNames := []string{}
-	for _, n := range nsList.Items {
-		namespacesNames = append(namespacesNames, n.Name)
+	if len(finalList) == 0 {
+		finalList = append(finalList, "")
 	}
-
-	return namespacesNames
+	return finalList, nil
 }
 
 func checkMessageSimilarity(conditions []policyv1.Condition, cond *policyv1.Condition) bool {
@@ -1064,56 +1746,70 @@ func objectExists(namespaced bool, namespace string, name string, rsrc schema.Gr
 }
 
 func createObject(namespaced bool, namespace string, name string, rsrc schema.GroupVersionResource,
-	unstruct unstructured.Unstructured, dclient dynamic.Interface) (result bool, erro error) {
+	unstruct unstructured.Unstructured, dclient dynamic.Interface, dryRun bool) (result bool, uid string, erro error) {
 	var err error
+	var obj *unstructured.Unstructured
 	created := false
+	createOptions := metav1.CreateOptions{FieldManager: fieldManager}
+	if dryRun {
+		createOptions.DryRun = []string{metav1.DryRunAll}
+	}
 	// set ownerReference for mutaionPolicy and override remediationAction
 
-	glog.V(6).Infof("createObject:  `%s`", unstruct)
+	glog.V(6).Infof("createObject:  `%s`", common.Redact(fmt.Sprint(unstruct)))
 
 	if !namespaced {
 		res := dclient.Resource(rsrc)
 
-		_, err = res.Create(context.TODO(), &unstruct, metav1.CreateOptions{})
+		obj, err = res.Create(context.TODO(), &unstruct, createOptions)
 		if err != nil {
 			if errors.IsAlreadyExists(err) {
 				created = true
 				glog.V(9).Infof("%v\n", err.Error())
 			} else {
-				glog.Errorf("!namespaced, full creation error: %s", err)
+				glog.Errorf("!namespaced, full creation error: %s", common.Redact(err.Error()))
 				glog.Errorf("Error creating the object `%v`, the error is `%v`", name, errors.ReasonForError(err))
 			}
 		} else {
 			created = true
+			uid = string(obj.GetUID())
 			glog.V(4).Infof("Resource `%v` created\n", name)
 		}
 	} else {
 		res := dclient.Resource(rsrc).Namespace(namespace)
-		_, err = res.Create(context.TODO(), &unstruct, metav1.CreateOptions{})
+		obj, err = res.Create(context.TODO(), &unstruct, createOptions)
 		if err != nil {
 			if errors.IsAlreadyExists(err) {
 				created = true
 				glog.V(9).Infof("%v\n", err.Error())
 			} else {
-				glog.Errorf("namespaced, full creation error: %s", err)
+				glog.Errorf("namespaced, full creation error: %s", common.Redact(err.Error()))
 				glog.Errorf("Error creating the object `%v`, the error is `%v`", name, errors.ReasonForError(err))
 			}
 		} else {
 			created = true
+			uid = string(obj.GetUID())
 			glog.V(4).Infof("Resource `%v` created\n", name)
 
 		}
 	}
-	return created, err
+	if err == nil || !errors.IsAlreadyExists(err) {
+		recordEnforcement(actionCreate, dryRun, err)
+	}
+	return created, uid, err
 }
 
 func deleteObject(namespaced bool, namespace string, name string, rsrc schema.GroupVersionResource,
-	dclient dynamic.Interface) (result bool, erro error) {
+	dclient dynamic.Interface, dryRun bool) (result bool, erro error) {
 	deleted := false
 	var err error
+	deleteOptions := metav1.DeleteOptions{}
+	if dryRun {
+		deleteOptions.DryRun = []string{metav1.DryRunAll}
+	}
 	if !namespaced {
 		res := dclient.Resource(rsrc)
-		err = res.Delete(context.TODO(), name, metav1.DeleteOptions{})
+		err = res.Delete(context.TODO(), name, deleteOptions)
 		if err != nil {
 			if errors.IsNotFound(err) {
 				deleted = true
@@ -1126,7 +1822,7 @@ func deleteObject(namespaced bool, namespace string, name string, rsrc schema.Gr
 		}
 	} else {
 		res := dclient.Resource(rsrc).Namespace(namespace)
-		err = res.Delete(context.TODO(), name, metav1.DeleteOptions{})
+		err = res.Delete(context.TODO(), name, deleteOptions)
 		if err != nil {
 			if errors.IsNotFound(err) {
 				deleted = true
@@ -1138,6 +1834,9 @@ func deleteObject(namespaced bool, namespace string, name string, rsrc schema.Gr
 			glog.V(5).Infof("object `%v` deleted from the api server\n", name)
 		}
 	}
+	if err == nil || !errors.IsNotFound(err) {
+		recordEnforcement(actionDelete, dryRun, err)
+	}
 	return deleted, err
 }
 
@@ -1388,14 +2087,19 @@ func handleSingleKey(key string, unstruct unstructured.Unstructured, existingObj
 
 func handleKeys(unstruct unstructured.Unstructured, existingObj *unstructured.Unstructured,
 	remediation policyv1.RemediationAction, complianceType string, typeStr string, name string,
-	res dynamic.ResourceInterface) (success bool, throwSpecViolation bool, message string,
-	processingErr bool) {
+	res dynamic.ResourceInterface, forceOwnership bool) (success bool, throwSpecViolation bool, message string,
+	processingErr bool, differences []policyv1.FieldDifference) {
 	var err error
+	// the changes of all the keys are sent in a single request, in a dry run they are also reported
+	dryRun := isDryRun(remediation)
+	specViolation := false
+	changedKeys := []string{}
+	original := existingObj.DeepCopy()
 	for key := range unstruct.Object {
 		isStatus := key == "status"
 		errorMsg, updateNeeded, mergedObj, skipped := handleSingleKey(key, unstruct, existingObj, complianceType)
 		if errorMsg != "" {
-			return false, false, errorMsg, true
+			return false, false, errorMsg, true, nil
 		}
 		if mergedObj == nil && skipped {
 			continue
@@ -1412,31 +2116,80 @@ func handleKeys(unstruct unstructured.Unstructured, existingObj *unstructured.Un
 		}
 		mapMtx.Unlock()
 		if updateNeeded {
+			// the other keys are still merged to report all the differences
 			if (strings.ToLower(string(remediation)) == strings.ToLower(string(policyv1.Inform))) || isStatus {
-				return false, true, "", false
-			}
-			//enforce
-			glog.V(4).Infof("Updating %v template `%v`...", typeStr, name)
-			_, err = res.Update(context.TODO(), existingObj, metav1.UpdateOptions{})
-			if errors.IsNotFound(err) {
-				message := fmt.Sprintf("`%v` is not present and must be created", typeStr)
-				return false, false, message, true
-			}
-			if err != nil {
-				message := fmt.Sprintf("Error updating the object `%v`, the error is `%v`", name, err)
-				return false, false, message, true
+				specViolation = true
 			}
-			glog.V(4).Infof("Resource `%v` updated\n", name)
+			changedKeys = append(changedKeys, key)
+		}
+	}
+	if len(changedKeys) == 0 {
+		return false, false, "", false, nil
+	}
+	// the values of a secret are not reported
+	differences = getObjectDifferences(original, existingObj, changedKeys, typeStr == "Secret")
+	if specViolation {
+		return false, true, "", false, differences
+	}
+	//enforce
+	glog.V(4).Infof("Updating %v template `%v` (dry run: %v)...", typeStr, name, dryRun)
+	var updatedObj *unstructured.Unstructured
+	if strings.ToLower(complianceType) == strings.ToLower(string(policyv1.MustOnlyHave)) {
+		// mustonlyhave also removes the fields owned by other field managers, so the whole object is replaced
+		updateOptions := metav1.UpdateOptions{FieldManager: fieldManager}
+		if dryRun {
+			updateOptions.DryRun = []string{metav1.DryRunAll}
+		}
+		updatedObj, err = res.Update(context.TODO(), existingObj, updateOptions)
+	} else {
+		// server-side apply only takes ownership of the fields set in the template
+		var payload []byte
+		payload, err = json.Marshal(getApplyPayload(unstruct, existingObj,
+			getListTypes(existingObj.GroupVersionKind())))
+		if err != nil {
+			message := fmt.Sprintf(convertJSONError, typeStr, err)
+			return false, false, message, true, nil
+		}
+		patchOptions := metav1.PatchOptions{FieldManager: fieldManager, Force: &forceOwnership}
+		if dryRun {
+			patchOptions.DryRun = []string{metav1.DryRunAll}
 		}
+		updatedObj, err = res.Patch(context.TODO(), name, types.ApplyPatchType, payload, patchOptions)
+	}
+	recordEnforcement(actionUpdate, dryRun, err)
+	if errors.IsNotFound(err) {
+		message := fmt.Sprintf("`%v` is not present and must be created", typeStr)
+		return false, false, message, true, nil
+	}
+	if errors.IsConflict(err) {
+		message := fmt.Sprintf("Error applying the object `%v`, fields of the template are managed by another "+
+			"field manager, set forceOwnership to take them over. The error is `%v`", name, err)
+		return false, false, message, true, differences
+	}
+	if err != nil {
+		message := fmt.Sprintf("Error updating the object `%v`, the error is `%v`", name, err)
+		return false, false, message, true, nil
 	}
-	return false, false, "", false
+	if dryRun {
+		// the object returned by the server has its defaults and the changes of the admission webhooks, and the
+		// lists merged like the apply, the local merge is only an estimate of them
+		if updatedObj != nil {
+			differences = getObjectDifferences(original, updatedObj, changedKeys, typeStr == "Secret")
+		}
+		if len(differences) == 0 {
+			return false, false, "", false, nil
+		}
+		return true, false, formatFieldDifferences(differences), false, differences
+	}
+	glog.V(4).Infof("Resource `%v` updated\n", name)
+	return false, false, "", false, nil
 }
 
 func updateTemplate(
 	complianceType string, metadata map[string]interface{}, remediation policyv1.RemediationAction,
 	rsrc schema.GroupVersionResource, dclient dynamic.Interface,
-	typeStr string, parent *policyv1.ConfigurationPolicy) (success bool, throwSpecViolation bool,
-	message string, processingErr bool) {
+	typeStr string, parent *policyv1.ConfigurationPolicy, forceOwnership bool) (success bool, throwSpecViolation bool,
+	message string, processingErr bool, differences []policyv1.FieldDifference) {
 	name := metadata["name"].(string)
 	namespace := metadata["namespace"].(string)
 	namespaced := metadata["namespaced"].(bool)
@@ -1452,9 +2205,9 @@ func updateTemplate(
 	if err != nil {
 		glog.Errorf(getObjError, name)
 	} else {
-		return handleKeys(unstruct, existingObj, remediation, complianceType, typeStr, name, res)
+		return handleKeys(unstruct, existingObj, remediation, complianceType, typeStr, name, res, forceOwnership)
 	}
-	return false, false, "", false
+	return false, false, "", false, nil
 }
 
 // AppendCondition check and appends conditions
@@ -1478,6 +2231,30 @@ func AppendCondition(conditions []policyv1.Condition, newCond *policyv1.Conditio
 	return conditions
 }
 
+// appendComplianceHistory adds a new condition at the head of the history of a template when the compliance
+// changed, keeping at most complianceHistorySize entries
+func appendComplianceHistory(history []policyv1.ComplianceHistory, cond *policyv1.Condition,
+	complianceState policyv1.ComplianceState) []policyv1.ComplianceHistory {
+	if complianceHistorySize == 0 {
+		return nil
+	}
+	// a new message with the same compliance is not a transition
+	if len(history) > 0 && history[0].ComplianceState == complianceState {
+		return history
+	}
+	entry := policyv1.ComplianceHistory{
+		Timestamp:       cond.LastTransitionTime,
+		ComplianceState: complianceState,
+		Reason:          cond.Reason,
+		Message:         cond.Message,
+	}
+	history = append([]policyv1.ComplianceHistory{entry}, history...)
+	if uint(len(history)) > complianceHistorySize {
+		history = history[:complianceHistorySize]
+	}
+	return history
+}
+
 func getRoleNames(list []rbacv1.Role) []string {
 	roleNames := []string{}
 	for _, n := range list {
@@ -1520,9 +2297,15 @@ func IsSimilarToLastCondition(oldCond policyv1.Condition, newCond policyv1.Condi
 	return false
 }
 
-func addForUpdate(policy *policyv1.ConfigurationPolicy) {
+// getComplianceState returns the compliance of a policy from the compliance of its templates
+func getComplianceState(policy *policyv1.ConfigurationPolicy) policyv1.ComplianceState {
 	compliant := true
-	for index := range policy.Spec.ObjectTemplates {
+	numTemplates := len(policy.Spec.ObjectTemplates)
+	if policy.Spec.ObjectTemplatesRaw != "" {
+		// the compliancy details are kept for the object templates generated by object-templates-raw only
+		numTemplates = len(policy.Status.CompliancyDetails)
+	}
+	for index := 0; index < numTemplates; index++ {
 		if index < len(policy.Status.CompliancyDetails) {
 			if policy.Status.CompliancyDetails[index].ComplianceState == policyv1.NonCompliant {
 				compliant = false
@@ -1530,12 +2313,16 @@ func addForUpdate(policy *policyv1.ConfigurationPolicy) {
 		}
 	}
 	if len(policy.Status.CompliancyDetails) == 0 {
-		policy.Status.ComplianceState = "Undetermined"
+		return "Undetermined"
 	} else if compliant {
-		policy.Status.ComplianceState = policyv1.Compliant
-	} else {
-		policy.Status.ComplianceState = policyv1.NonCompliant
+		return policyv1.Compliant
 	}
+	return policyv1.NonCompliant
+}
+
+func addForUpdate(policy *policyv1.ConfigurationPolicy) {
+	policy.Status.ComplianceState = getComplianceState(policy)
+	policy.Status.Severity = policy.Spec.Severity
 	_, err := updatePolicyStatus(map[string]*policyv1.ConfigurationPolicy{
 		(*policy).GetName(): policy,
 	})
@@ -1555,15 +2342,19 @@ func updatePolicyStatus(policies map[string]*policyv1.ConfigurationPolicy) (*pol
 			createParentPolicyEvent(instance)
 		}
 		if reconcilingAgent.recorder != nil {
-			reconcilingAgent.recorder.Event(instance, "Normal", "Policy updated", fmt.Sprintf("Policy status is: %v",
-				instance.Status.ComplianceState))
+			eventType := eventNormal
+			if instance.Status.ComplianceState == policyv1.NonCompliant && instance.Spec.Severity.IsHigh() {
+				eventType = eventWarning
+			}
+			recordPolicyEvent(reconcilingAgent.recorder, instance, eventType, "Policy updated",
+				fmt.Sprintf("Policy status is: %v", instance.Status.ComplianceState))
 		}
 	}
 	return nil, nil
 }
 
 func handleRemovingPolicy(name string) {
-	for k, v := range availablePolicies.PolicyMap {
+	for k, v := range availablePolicies.GetAll() {
 		if v.Name == name {
 			availablePolicies.RemoveObject(k)
 		}
@@ -1577,6 +2368,11 @@ func handleAddingPolicy(plc *policyv1.ConfigurationPolicy) error {
 			" subject: K8s API server, namespace: all, according to policy: %v, additional-info: %v", plc.Name, err)
 		return err
 	}
+	// an invalid selector selects no namespaces, the violation is reported when the policy is evaluated
+	selectedNamespaces, selectErr := common.SelectNamespaces(plc.Spec.NamespaceSelector, plc.Spec.LabelSelector)
+	if selectErr != nil {
+		glog.Errorf("Error selecting the namespaces of policy %s: %v", plc.Name, selectErr)
+	}
 	//clean up that policy from the existing namepsaces, in case the modification is in the namespace selector
 	for _, ns := range allNamespaces {
 		key := fmt.Sprintf("%s/%s", ns, plc.Name)
@@ -1586,8 +2382,6 @@ func handleAddingPolicy(plc *policyv1.ConfigurationPolicy) error {
 			}
 		}
 	}
-	selectedNamespaces := common.GetSelectedNamespaces(plc.Spec.NamespaceSelector.Include,
-		plc.Spec.NamespaceSelector.Exclude, allNamespaces)
 	for _, ns := range selectedNamespaces {
 		key := fmt.Sprintf("%s/%s", ns, plc.Name)
 		availablePolicies.AddObject(key, plc)
@@ -1616,10 +2410,10 @@ func join(strs ...string) string {
 // Helper functions that pretty prints a map
 func printMap(myMap map[string]*policyv1.ConfigurationPolicy) {
 	if len(myMap) == 0 {
-		fmt.Println("Waiting for policies to be available for processing... ")
+		glog.V(3).Info("Waiting for policies to be available for processing... ")
 		return
 	}
-	fmt.Println("Available policies in namespaces: ")
+	glog.V(3).Info("Available policies in namespaces: ")
 
 	mapToPrint := map[string][]string{}
 	for k, v := range myMap {
@@ -1639,8 +2433,25 @@ func printMap(myMap map[string]*policyv1.ConfigurationPolicy) {
 			}
 		}
 		nsString += "]"
-		fmt.Println(fmt.Sprintf("configpolicy %s in namespace(s) %s", k, nsString))
+		glog.V(3).Infof("configpolicy %s in namespace(s) %s", k, nsString)
+	}
+}
+
+// severityAnnotation gives the severity of the policy on its events
+const severityAnnotation string = "policy.open-cluster-management.io/severity"
+
+// recordPolicyEvent records an event on the policy annotated with its severity, the reason of the warnings
+// of the high and critical policies starts with the severity so that they stand out
+func recordPolicyEvent(rec record.EventRecorder, plc *policyv1.ConfigurationPolicy, eventType string, reason string,
+	message string) {
+	if rec == nil {
+		return
+	}
+	if eventType == eventWarning && plc.Spec.Severity.IsHigh() {
+		reason = fmt.Sprintf("[%s] %s", plc.Spec.Severity, reason)
 	}
+	rec.AnnotatedEventf(plc, map[string]string{severityAnnotation: string(plc.Spec.Severity)}, eventType, reason,
+		"%s", common.Redact(message))
 }
 
 func createParentPolicyEvent(instance *policyv1.ConfigurationPolicy) {
@@ -1659,10 +2470,12 @@ func createParentPolicyEvent(instance *policyv1.ConfigurationPolicy) {
 		if instance.Status.ComplianceState == policyv1.NonCompliant {
 			eventType = "Warning"
 		}
-		reconcilingAgent.recorder.Event(&parentPlc,
+		// the reason identifies the configuration policy on the parent, so it does not give the severity
+		reconcilingAgent.recorder.AnnotatedEventf(&parentPlc,
+			map[string]string{severityAnnotation: string(instance.Spec.Severity)},
 			eventType,
 			fmt.Sprintf(eventFmtStr, instance.Namespace, instance.Name),
-			convertPolicyStatusToString(instance))
+			"%s", common.Redact(convertPolicyStatusToString(instance)))
 	}
 }
 
@@ -1714,6 +2527,6 @@ func convertPolicyStatusToString(plc *policyv1.ConfigurationPolicy) (results str
 
 func recoverFlow() {
 	if r := recover(); r != nil {
-		fmt.Println("ALERT!!!! -> recovered from ", r)
+		glog.Errorf("ALERT!!!! -> recovered from %v", r)
 	}
 }
@@ -5,10 +5,12 @@ package configurationpolicy
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	policiesv1alpha1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
 	"github.com/open-cluster-management/config-policy-controller/pkg/common"
@@ -16,11 +18,16 @@ import (
 	coretypes "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes"
 	testclient "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -83,6 +90,49 @@ func TestReconcile(t *testing.T) {
 	t.Log(res)
 }
 
+func TestReconcileStatusUpdate(t *testing.T) {
+	instance := &policiesv1alpha1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "status-update", Namespace: "default", Generation: 1},
+		Spec: policiesv1alpha1.ConfigurationPolicySpec{
+			NamespaceSelector: policiesv1alpha1.Target{Include: []string{"default"}},
+			RemediationAction: "inform",
+		},
+	}
+	s := scheme.Scheme
+	s.AddKnownTypes(policiesv1alpha1.SchemeGroupVersion, instance)
+	cl := fake.NewFakeClient(instance.DeepCopy())
+	r := &ReconcileConfigurationPolicy{client: cl, scheme: s, recorder: nil}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "status-update", Namespace: "default"}}
+	var simpleClient kubernetes.Interface = testclient.NewSimpleClientset()
+	common.Initialize(&simpleClient, nil)
+	objWatcher = newObjectWatcher(nil)
+	defer func() {
+		objWatcher = nil
+		handleRemovingPolicy("status-update")
+	}()
+
+	// a new policy is evaluated
+	_, err := r.Reconcile(req)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"default/status-update"}, objWatcher.popPending())
+
+	// the status updates of its evaluations don't evaluate it again
+	instance.Status.ComplianceState = policiesv1alpha1.Compliant
+	assert.Nil(t, cl.Status().Update(context.TODO(), instance))
+	_, err = r.Reconcile(req)
+	assert.Nil(t, err)
+	assert.Empty(t, objWatcher.popPending())
+
+	// a change to its spec is evaluated
+	assert.Nil(t, cl.Get(context.TODO(), req.NamespacedName, instance))
+	instance.Spec.RemediationAction = "enforce"
+	instance.SetGeneration(2)
+	assert.Nil(t, cl.Update(context.TODO(), instance))
+	_, err = r.Reconcile(req)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"default/status-update"}, objWatcher.popPending())
+}
+
 // func TestHandleObjectTemplates(t *testing.T) {
 // 	var typeMeta = metav1.TypeMeta{
 // 		Kind: "namespace",
@@ -204,7 +254,7 @@ func TestReconcile(t *testing.T) {
 // 	samplePolicy.Spec.NamespaceSelector.Include = target
 // 	err = handleAddingPolicy(&samplePolicy)
 // 	assert.Nil(t, err)
-// 	PeriodicallyExecConfigPolicies(1, true)
+// 	PeriodicallyExecConfigPolicies(1, 1, true)
 // }
 
 func TestCompareSpecs(t *testing.T) {
@@ -612,7 +662,7 @@ func TestCreateInformStatus(t *testing.T) {
 
 	// Test 1 NonCompliant resource
 	createInformStatus(mustNotHave, numCompliant, numNonCompliant,
-		compliantObjects, nonCompliantObjects, policy, objData)
+		compliantObjects, nonCompliantObjects, policy, objData, nil)
 	assert.True(t, policy.Status.CompliancyDetails[0].ComplianceState == policiesv1alpha1.NonCompliant)
 
 	nonCompliantObjects["test2"] = map[string]interface{}{
@@ -623,7 +673,7 @@ func TestCreateInformStatus(t *testing.T) {
 
 	// Test 2 NonCompliant resources
 	createInformStatus(mustNotHave, numCompliant, numNonCompliant,
-		compliantObjects, nonCompliantObjects, policy, objData)
+		compliantObjects, nonCompliantObjects, policy, objData, nil)
 	assert.True(t, policy.Status.CompliancyDetails[0].ComplianceState == policiesv1alpha1.NonCompliant)
 
 	delete(nonCompliantObjects, "test1")
@@ -632,7 +682,7 @@ func TestCreateInformStatus(t *testing.T) {
 	// Test 0 resources
 	numNonCompliant = 0
 	createInformStatus(mustNotHave, numCompliant, numNonCompliant,
-		compliantObjects, nonCompliantObjects, policy, objData)
+		compliantObjects, nonCompliantObjects, policy, objData, nil)
 	assert.True(t, policy.Status.CompliancyDetails[0].ComplianceState == policiesv1alpha1.NonCompliant)
 
 	compliantObjects["test1"] = map[string]interface{}{
@@ -648,7 +698,7 @@ func TestCreateInformStatus(t *testing.T) {
 
 	// Test 1 compliant and 1 noncompliant resource  NOTE: This use case is the new behavior change!
 	createInformStatus(mustNotHave, numCompliant, numNonCompliant,
-		compliantObjects, nonCompliantObjects, policy, objData)
+		compliantObjects, nonCompliantObjects, policy, objData, nil)
 	assert.True(t, policy.Status.CompliancyDetails[0].ComplianceState == policiesv1alpha1.NonCompliant)
 
 	compliantObjects["test2"] = map[string]interface{}{
@@ -661,6 +711,590 @@ func TestCreateInformStatus(t *testing.T) {
 
 	// Test 2 compliant resources
 	createInformStatus(mustNotHave, numCompliant, numNonCompliant,
-		compliantObjects, nonCompliantObjects, policy, objData)
+		compliantObjects, nonCompliantObjects, policy, objData, nil)
 	assert.True(t, policy.Status.CompliancyDetails[0].ComplianceState == policiesv1alpha1.Compliant)
 }
+
+func TestShouldEvaluatePolicy(t *testing.T) {
+	policy := &policiesv1alpha1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "foo",
+			Namespace:  "default",
+			Generation: 1,
+		},
+		Spec: policiesv1alpha1.ConfigurationPolicySpec{
+			EvaluationInterval: policiesv1alpha1.EvaluationInterval{
+				Compliant:    "never",
+				NonCompliant: "1h",
+			},
+		},
+	}
+	defer delete(lastEvaluations, policyKey(policy))
+
+	// a policy never evaluated is always due
+	assert.True(t, shouldEvaluatePolicy(policy, false))
+
+	lastEvaluations[policyKey(policy)] = evaluationRecord{time: time.Now().Add(-time.Minute), generation: 1}
+	policy.Status.ComplianceState = policiesv1alpha1.NonCompliant
+	assert.False(t, shouldEvaluatePolicy(policy, true))
+	next, ok := nextEvaluation(map[string]*policiesv1alpha1.ConfigurationPolicy{"foo": policy})
+	assert.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(59*time.Minute), next, 5*time.Second)
+
+	policy.Spec.EvaluationInterval.NonCompliant = "30s"
+	assert.True(t, shouldEvaluatePolicy(policy, false))
+
+	policy.Status.ComplianceState = policiesv1alpha1.Compliant
+	assert.False(t, shouldEvaluatePolicy(policy, true))
+	_, ok = nextEvaluation(map[string]*policiesv1alpha1.ConfigurationPolicy{"foo": policy})
+	assert.False(t, ok)
+
+	assert.False(t, shouldEvaluateChangedPolicy(policy))
+
+	// a change to the objects of a compliant policy is evaluated before its interval elapses
+	policy.Spec.EvaluationInterval.Compliant = "1h"
+	assert.False(t, shouldEvaluatePolicy(policy, true))
+	assert.True(t, shouldEvaluateChangedPolicy(policy))
+	policy.Spec.EvaluationInterval.NonCompliant = "1h"
+	policy.Status.ComplianceState = policiesv1alpha1.NonCompliant
+	assert.False(t, shouldEvaluateChangedPolicy(policy))
+	policy.Status.ComplianceState = policiesv1alpha1.Compliant
+
+	// a policy without an interval is evaluated on every resync
+	policy.Spec.EvaluationInterval.Compliant = ""
+	assert.False(t, shouldEvaluatePolicy(policy, false))
+	assert.True(t, shouldEvaluatePolicy(policy, true))
+
+	// a spec change is always evaluated
+	policy.Spec.EvaluationInterval.Compliant = "never"
+	policy.Generation = 2
+	assert.True(t, shouldEvaluatePolicy(policy, false))
+}
+
+func TestGetFieldDifferences(t *testing.T) {
+	actual := map[string]interface{}{
+		"data": map[string]interface{}{
+			"kept":    "same",
+			"changed": "old",
+			"removed": "gone",
+		},
+		"metadata": map[string]interface{}{
+			"annotations": nil,
+		},
+	}
+	expected := map[string]interface{}{
+		"data": map[string]interface{}{
+			"kept":    "same",
+			"changed": "new",
+			"added":   []interface{}{"a", "b"},
+		},
+		"metadata": map[string]interface{}{},
+	}
+	differences := getFieldDifferences("", actual, expected, false)
+	assert.Equal(t, []policiesv1alpha1.FieldDifference{
+		{Path: "data.added", Expected: `["a","b"]`},
+		{Path: "data.changed", Expected: `"new"`, Actual: `"old"`},
+		{Path: "data.removed", Actual: `"gone"`},
+	}, differences)
+	assert.Equal(t, `data.added: <none> -> ["a","b"]; data.changed: "old" -> "new"; data.removed: "gone" -> <none>`,
+		formatFieldDifferences(differences))
+	assert.Equal(t, []policiesv1alpha1.FieldDifference{
+		{Path: "data.added", Expected: "<redacted>"},
+		{Path: "data.changed", Expected: "<redacted>", Actual: "<redacted>"},
+		{Path: "data.removed", Actual: "<redacted>"},
+	}, getFieldDifferences("", actual, expected, true))
+	assert.Empty(t, getFieldDifferences("", actual, actual, false))
+
+	// the secret values read by the templates are redacted from the other kinds
+	common.TrackSecretValue("templatedpassword")
+	assert.Equal(t, []policiesv1alpha1.FieldDifference{
+		{Path: "data.changed", Expected: `"<redacted>"`, Actual: `"old"`},
+	}, getFieldDifferences("", map[string]interface{}{"data": map[string]interface{}{"changed": "old"}},
+		map[string]interface{}{"data": map[string]interface{}{"changed": "templatedpassword"}}, false))
+}
+
+func TestHandleKeysInformDifferences(t *testing.T) {
+	existing := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"name":      "foo",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"app": "bar"},
+		},
+		"data": map[string]interface{}{
+			"password": "b2xk",
+		},
+	}}
+	desired := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Secret",
+		"metadata": map[string]interface{}{
+			"name":   "foo",
+			"labels": map[string]interface{}{"app": "foo"},
+		},
+		"data": map[string]interface{}{
+			"password": "bmV3",
+		},
+	}}
+
+	// all the differences are reported, with the values of the secret redacted
+	updated, specViolation, msg, processingErr, differences := handleKeys(desired, existing, policiesv1alpha1.Inform,
+		"musthave", "Secret", "foo", nil, false)
+	assert.False(t, updated)
+	assert.True(t, specViolation)
+	assert.False(t, processingErr)
+	assert.Equal(t, "", msg)
+	assert.Equal(t, []policiesv1alpha1.FieldDifference{
+		{Path: "data.password", Expected: "<redacted>", Actual: "<redacted>"},
+		{Path: "metadata.labels.app", Expected: "<redacted>", Actual: "<redacted>"},
+	}, differences)
+}
+
+func TestHandleKeysDryRun(t *testing.T) {
+	rsrc := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	existing := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "foo",
+			"namespace": "default",
+		},
+		"data": map[string]interface{}{
+			"key": "old",
+		},
+	}}
+	desired := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":   "foo",
+			"labels": map[string]interface{}{"app": "foo"},
+		},
+		"data": map[string]interface{}{
+			"key": "new",
+		},
+	}}
+	dclient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), existing.DeepCopy())
+	// the fake client does not support server-side apply, the server also sets a field of a webhook
+	var applied map[string]interface{}
+	dryRunResult := existing.DeepCopy()
+	assert.Nil(t, unstructured.SetNestedField(dryRunResult.Object, "new", "data", "key"))
+	assert.Nil(t, unstructured.SetNestedField(dryRunResult.Object, "true", "data", "injected"))
+	assert.Nil(t, unstructured.SetNestedField(dryRunResult.Object, "foo", "metadata", "labels", "app"))
+	dclient.PrependReactor("patch", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		patch := action.(clienttesting.PatchAction)
+		assert.Equal(t, types.ApplyPatchType, patch.GetPatchType())
+		assert.Nil(t, json.Unmarshal(patch.GetPatch(), &applied))
+		return true, dryRunResult, nil
+	})
+	res := dclient.Resource(rsrc).Namespace("default")
+
+	// the differences are the ones of the object returned by the server
+	updated, specViolation, msg, processingErr, differences := handleKeys(desired, existing.DeepCopy(),
+		policiesv1alpha1.DryRun, "musthave", "ConfigMap", "foo", res, false)
+	assert.True(t, updated)
+	assert.False(t, specViolation)
+	assert.False(t, processingErr)
+	assert.Equal(t, `data.injected: <none> -> "true"; data.key: "old" -> "new"; metadata.labels: <none> -> `+
+		`{"app":"foo"}`, msg)
+	assert.Len(t, differences, 3)
+
+	// the changes of all the keys are sent in a single apply, with only the fields of the template
+	patches := 0
+	for _, action := range dclient.Actions() {
+		if action.GetVerb() == "patch" {
+			patches++
+		}
+	}
+	assert.Equal(t, 1, patches)
+	assert.Equal(t, map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "foo",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"app": "foo"},
+		},
+		"data": map[string]interface{}{
+			"key": "new",
+		},
+	}, applied)
+
+	// the server leaves the object unchanged, such as when it normalizes the values of the template
+	dryRunResult = existing.DeepCopy()
+	updated, specViolation, msg, processingErr, differences = handleKeys(desired, existing.DeepCopy(),
+		policiesv1alpha1.DryRun, "musthave", "ConfigMap", "foo", res, false)
+	assert.False(t, updated)
+	assert.False(t, specViolation)
+	assert.False(t, processingErr)
+	assert.Equal(t, "", msg)
+	assert.Nil(t, differences)
+}
+
+func TestHandleSingleObjCreatedByPolicy(t *testing.T) {
+	rsrc := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	desired := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "foo", "namespace": "default"},
+	}}
+	dclient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	// the API server returns a UID for the dry run creates as well
+	dclient.PrependReactor("create", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		created := action.(clienttesting.CreateAction).GetObject().(*unstructured.Unstructured).DeepCopy()
+		created.SetUID("created-uid")
+		return true, created, nil
+	})
+	data := map[string]interface{}{
+		"name":       "foo",
+		"namespace":  "default",
+		"namespaced": true,
+		"index":      0,
+		"unstruct":   desired,
+	}
+	newPolicy := func() *policiesv1alpha1.ConfigurationPolicy {
+		return &policiesv1alpha1.ConfigurationPolicy{
+			Spec: policiesv1alpha1.ConfigurationPolicySpec{ObjectTemplates: []*policiesv1alpha1.ObjectTemplate{{}}},
+		}
+	}
+
+	_, _, _, _, details := handleSingleObj(newPolicy(), record.NewFakeRecorder(1), policiesv1alpha1.DryRun, false,
+		true, rsrc, dclient, &policiesv1alpha1.ObjectTemplate{}, data)
+	assert.Nil(t, details.Properties)
+
+	_, _, _, _, details = handleSingleObj(newPolicy(), record.NewFakeRecorder(1), policiesv1alpha1.Enforce, false,
+		true, rsrc, dclient, &policiesv1alpha1.ObjectTemplate{}, data)
+	assert.Equal(t, &policiesv1alpha1.ObjectProperties{CreatedByPolicy: true, UID: "created-uid"}, details.Properties)
+}
+
+func TestHandleSingleObjAssertionsAfterUpdate(t *testing.T) {
+	rsrc := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	existing := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "foo", "namespace": "default"},
+		"data":       map[string]interface{}{"key": "old"},
+	}}
+	desired := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "foo", "namespace": "default"},
+		"data":       map[string]interface{}{"key": "new"},
+	}}
+	dclient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	// the fake client does not support server-side apply, the applied data replaces the data of the object
+	current := existing.DeepCopy()
+	dclient.PrependReactor("get", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, current.DeepCopy(), nil
+	})
+	dclient.PrependReactor("patch", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		applied := map[string]interface{}{}
+		assert.Nil(t, json.Unmarshal(action.(clienttesting.PatchAction).GetPatch(), &applied))
+		current.Object["data"] = applied["data"]
+		return true, current.DeepCopy(), nil
+	})
+	objectT := &policiesv1alpha1.ObjectTemplate{
+		ComplianceType: policiesv1alpha1.MustHave,
+		Assertions: []policiesv1alpha1.Assertion{
+			{Path: ".data.key", Operator: policiesv1alpha1.AssertEquals, Value: "new"},
+		},
+	}
+	policy := &policiesv1alpha1.ConfigurationPolicy{
+		Spec: policiesv1alpha1.ConfigurationPolicySpec{ObjectTemplates: []*policiesv1alpha1.ObjectTemplate{objectT}},
+	}
+
+	// the assertions are checked on the object updated by the enforced template
+	_, compliant, _, _, details := handleSingleObj(policy, record.NewFakeRecorder(1), policiesv1alpha1.Enforce,
+		true, true, rsrc, dclient, objectT, map[string]interface{}{
+			"name":       "foo",
+			"namespace":  "default",
+			"namespaced": true,
+			"index":      0,
+			"unstruct":   desired,
+		})
+	assert.True(t, compliant)
+	assert.Empty(t, details.FailedAssertions)
+}
+
+func TestGetApplyPayload(t *testing.T) {
+	tmpl := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name": "foo",
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "nginx", "image": "nginx:1.7.9"},
+			},
+		},
+	}}
+	merged := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":      "foo",
+			"namespace": "default",
+			"labels":    map[string]interface{}{"owner": "someone-else"},
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "nginx", "image": "nginx:1.7.9"},
+				map[string]interface{}{"name": "sidecar", "image": "sidecar"},
+			},
+			"nodeName": "node1",
+		},
+		"status": map[string]interface{}{"phase": "Running"},
+	}}
+	containers := func(path []string) (bool, []string) {
+		if strings.Join(path, ".") == "spec.containers" {
+			return true, []string{"name"}
+		}
+		return false, nil
+	}
+	// the fields and the list items the template does not set are not applied
+	assert.Equal(t, map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":      "foo",
+			"namespace": "default",
+		},
+		"spec": map[string]interface{}{
+			"containers": []interface{}{
+				map[string]interface{}{"name": "nginx", "image": "nginx:1.7.9"},
+			},
+		},
+	}, getApplyPayload(tmpl, merged, containers))
+
+	// the atomic lists, the lists of an unknown schema and the lists whose template items miss their keys keep
+	// their merged items
+	mergedContainers := []interface{}{
+		map[string]interface{}{"name": "nginx", "image": "nginx:1.7.9"},
+		map[string]interface{}{"name": "sidecar", "image": "sidecar"},
+	}
+	appliedContainers := func(tmpl unstructured.Unstructured, lists listTypes) interface{} {
+		return getApplyPayload(tmpl, merged, lists)["spec"].(map[string]interface{})["containers"]
+	}
+	atomic := func(path []string) (bool, []string) { return false, nil }
+	assert.Equal(t, mergedContainers, appliedContainers(tmpl, atomic))
+	assert.Equal(t, mergedContainers, appliedContainers(tmpl, nil))
+	noName := tmpl.DeepCopy()
+	noName.Object["spec"] = map[string]interface{}{
+		"containers": []interface{}{map[string]interface{}{"image": "nginx:1.7.9"}},
+	}
+	assert.Equal(t, mergedContainers, appliedContainers(*noName, containers))
+}
+
+func TestRemoveForbiddenFields(t *testing.T) {
+	existing := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":        "foo",
+			"namespace":   "default",
+			"labels":      map[string]interface{}{"app": "foo", "debug": "true"},
+			"annotations": map[string]interface{}{"forbidden": "yes", "allowed": "yes"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": int64(3),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"name":            "nginx",
+							"securityContext": map[string]interface{}{"privileged": true, "runAsUser": int64(0)},
+						},
+						map[string]interface{}{
+							"name":            "sidecar",
+							"securityContext": map[string]interface{}{"privileged": false},
+						},
+					},
+					"tolerations": []interface{}{
+						map[string]interface{}{"key": "master", "effect": "NoSchedule"},
+						map[string]interface{}{"key": "other", "effect": "NoSchedule"},
+					},
+				},
+			},
+		},
+	}}
+	forbidden := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata": map[string]interface{}{
+			"name":        "foo",
+			"labels":      map[string]interface{}{"debug": nil},
+			"annotations": map[string]interface{}{"forbidden": "yes", "allowed": "no"},
+		},
+		"spec": map[string]interface{}{
+			// the number of the template is not decoded to the same type as the one of the object
+			"replicas": float64(2),
+			"template": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"containers": []interface{}{
+						map[string]interface{}{
+							"securityContext": map[string]interface{}{"privileged": true},
+						},
+					},
+					"tolerations": []interface{}{
+						map[string]interface{}{"key": "master"},
+					},
+				},
+			},
+		},
+	}}
+
+	remaining, differences := removeForbiddenFields(forbidden, existing, false)
+	assert.Equal(t, []policiesv1alpha1.FieldDifference{
+		{Path: "metadata.annotations.forbidden", Actual: `"yes"`},
+		{Path: "metadata.labels.debug", Actual: `"true"`},
+		{Path: "spec.template.spec.containers[0].securityContext.privileged", Actual: "true"},
+		{Path: "spec.template.spec.tolerations[0]", Actual: `{"effect":"NoSchedule","key":"master"}`},
+	}, differences)
+	metadata := remaining.Object["metadata"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"app": "foo"}, metadata["labels"])
+	assert.Equal(t, map[string]interface{}{"allowed": "yes"}, metadata["annotations"])
+	podSpec := remaining.Object["spec"].(map[string]interface{})["template"].(map[string]interface{})["spec"]
+	assert.Equal(t, map[string]interface{}{
+		"containers": []interface{}{
+			map[string]interface{}{
+				"name":            "nginx",
+				"securityContext": map[string]interface{}{"runAsUser": int64(0)},
+			},
+			map[string]interface{}{
+				"name":            "sidecar",
+				"securityContext": map[string]interface{}{"privileged": false},
+			},
+		},
+		"tolerations": []interface{}{
+			map[string]interface{}{"key": "other", "effect": "NoSchedule"},
+		},
+	}, podSpec)
+	assert.Equal(t, int64(3), remaining.Object["spec"].(map[string]interface{})["replicas"])
+	// the existing object is not modified
+	assert.Equal(t, "true", existing.GetLabels()["debug"])
+
+	_, differences = removeForbiddenFields(forbidden, remaining, false)
+	assert.Empty(t, differences)
+}
+
+func TestHandleExistsMustNotHaveFields(t *testing.T) {
+	rsrc := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	existing := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      "foo",
+			"namespace": "default",
+		},
+		"data": map[string]interface{}{
+			"token": "forbidden",
+			"mode":  "allowed",
+		},
+	}}
+	forbidden := unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "foo"},
+		"data":       map[string]interface{}{"token": nil},
+	}}
+	dclient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), existing.DeepCopy())
+	plc := &policiesv1alpha1.ConfigurationPolicy{
+		Spec: policiesv1alpha1.ConfigurationPolicySpec{
+			RemediationAction: policiesv1alpha1.Inform,
+			ObjectTemplates:   []*policiesv1alpha1.ObjectTemplate{{}},
+		},
+	}
+	data := map[string]interface{}{
+		"name":       "foo",
+		"namespace":  "default",
+		"namespaced": true,
+		"index":      0,
+		"unstruct":   forbidden,
+	}
+
+	update, compliant, differences, err := handleExistsMustNotHaveFields(plc, policiesv1alpha1.Inform, rsrc, dclient,
+		data)
+	assert.Nil(t, err)
+	assert.False(t, update)
+	assert.False(t, compliant)
+	assert.Equal(t, []policiesv1alpha1.FieldDifference{{Path: "data.token", Actual: `"forbidden"`}}, differences)
+
+	update, compliant, _, err = handleExistsMustNotHaveFields(plc, policiesv1alpha1.Enforce, rsrc, dclient, data)
+	assert.Nil(t, err)
+	assert.True(t, update)
+	assert.True(t, compliant)
+	assert.Equal(t, "K8s update success", plc.Status.CompliancyDetails[0].Conditions[0].Reason)
+	updated, err := dclient.Resource(rsrc).Namespace("default").Get(context.TODO(), "foo", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{"mode": "allowed"}, updated.Object["data"])
+
+	_, compliant, differences, err = handleExistsMustNotHaveFields(plc, policiesv1alpha1.Enforce, rsrc, dclient,
+		data)
+	assert.Nil(t, err)
+	assert.True(t, compliant)
+	assert.Empty(t, differences)
+}
+
+func TestComplianceHistory(t *testing.T) {
+	defer InitializeComplianceHistory(complianceHistorySize)
+	InitializeComplianceHistory(2)
+	plc := &policiesv1alpha1.ConfigurationPolicy{}
+
+	assert.True(t, createViolation(plc, 0, "K8s does not have a `must have` object", "configmaps not found"))
+	// the same condition is not a change
+	assert.False(t, createViolation(plc, 0, "K8s does not have a `must have` object", "configmaps not found"))
+	assert.True(t, createNotification(plc, 0, "K8s creation success", "configmaps was created"))
+	assert.True(t, createViolation(plc, 0, "K8s has a `must not have` object", "configmaps found"))
+	// a new message with the same compliance is not kept in the history
+	assert.True(t, createViolation(plc, 0, "K8s has a `must not have` object", "configmaps found again"))
+
+	details := plc.Status.CompliancyDetails[0]
+	assert.Len(t, details.Conditions, 1)
+	assert.Equal(t, "configmaps found again", details.Conditions[0].Message)
+	assert.Len(t, details.History, 2)
+	assert.Equal(t, policiesv1alpha1.NonCompliant, details.History[0].ComplianceState)
+	assert.Equal(t, "configmaps found", details.History[0].Message)
+	assert.Equal(t, policiesv1alpha1.Compliant, details.History[1].ComplianceState)
+	assert.Equal(t, "K8s creation success", details.History[1].Reason)
+	assert.False(t, details.History[1].Timestamp.IsZero())
+
+	InitializeComplianceHistory(0)
+	assert.True(t, createNotification(plc, 0, "K8s creation success", "configmaps was created"))
+	assert.Empty(t, plc.Status.CompliancyDetails[0].History)
+}
+
+func TestRecordPolicyEvent(t *testing.T) {
+	rec := record.NewFakeRecorder(3)
+	plc := &policiesv1alpha1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"},
+		Spec:       policiesv1alpha1.ConfigurationPolicySpec{Severity: policiesv1alpha1.SeverityLow},
+	}
+
+	recordPolicyEvent(rec, plc, eventWarning, "policy: foo", "NonCompliant")
+	assert.Equal(t, "Warning policy: foo NonCompliant", <-rec.Events)
+	// the warnings of the high and critical policies give their severity
+	plc.Spec.Severity = policiesv1alpha1.SeverityCritical
+	recordPolicyEvent(rec, plc, eventWarning, "policy: foo", "NonCompliant")
+	assert.Equal(t, "Warning [critical] policy: foo NonCompliant", <-rec.Events)
+	recordPolicyEvent(rec, plc, eventNormal, "policy: foo", "Compliant")
+	assert.Equal(t, "Normal policy: foo Compliant", <-rec.Events)
+}
+
+func TestRedactedStatusAndEvents(t *testing.T) {
+	common.TrackSecretObject(map[string]interface{}{
+		"kind":       "Secret",
+		"stringData": map[string]interface{}{"password": "statuspassword"},
+	})
+	plc := &policiesv1alpha1.ConfigurationPolicy{ObjectMeta: metav1.ObjectMeta{Name: "foo", Namespace: "default"}}
+
+	assert.True(t, createViolation(plc, 0, "K8s update template error", "invalid value statuspassword"))
+	// the redacted message is the same condition
+	assert.False(t, createViolation(plc, 0, "K8s update template error", "invalid value statuspassword"))
+	assert.Equal(t, "invalid value <redacted>", plc.Status.CompliancyDetails[0].Conditions[0].Message)
+
+	rec := record.NewFakeRecorder(1)
+	recordPolicyEvent(rec, plc, eventWarning, "policy: foo", "NonCompliant; statuspassword")
+	assert.Equal(t, "Warning policy: foo NonCompliant; <redacted>", <-rec.Events)
+}
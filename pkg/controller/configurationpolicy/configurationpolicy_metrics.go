@@ -0,0 +1,162 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"strconv"
+	"sync"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// the metrics are served on the metrics endpoint of the manager
+var (
+	policyComplianceGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "config_policy_compliance",
+			Help: "The compliance of the policy, 0 when it is compliant and 1 when it is noncompliant",
+		},
+		[]string{"policy_namespace", "policy", "severity"},
+	)
+	templateComplianceGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "config_policy_template_compliance",
+			Help: "The compliance of the object template, 0 when it is compliant and 1 when it is noncompliant",
+		},
+		[]string{"policy_namespace", "policy", "template_index", "severity"},
+	)
+	evaluationSecondsHistogram = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "config_policy_evaluation_seconds",
+			Help:    "The time it took to evaluate the policy",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+		},
+		[]string{"policy_namespace", "policy"},
+	)
+	enforcementCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "config_policy_enforcement_actions_total",
+			Help: "The number of objects created, updated and deleted to enforce the policies, dry runs excluded",
+		},
+		[]string{"action", "result"},
+	)
+	templateResolutionFailuresCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "config_policy_template_resolution_failures_total",
+			Help: "The number of failures to resolve the templates of the policy",
+		},
+		[]string{"policy_namespace", "policy"},
+	)
+	discoveryFailuresCounter = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "config_policy_discovery_failures_total",
+			Help: "The number of failures to discover the API resources of the cluster",
+		},
+	)
+)
+
+// the enforcement actions and their results
+const (
+	actionCreate  = "create"
+	actionUpdate  = "update"
+	actionDelete  = "delete"
+	resultSuccess = "success"
+	resultFailure = "failure"
+)
+
+// complianceMetrics are the labels of the compliance metrics set for a policy
+type complianceMetrics struct {
+	templates int
+	severity  string
+}
+
+// metricPolicies holds the compliance metrics set for each policy key, to remove the metrics of the templates
+// removed from a policy, or with a previous severity
+var metricPolicies = map[string]complianceMetrics{}
+var metricPoliciesMx sync.Mutex
+
+func init() {
+	metrics.Registry.MustRegister(
+		policyComplianceGauge,
+		templateComplianceGauge,
+		evaluationSecondsHistogram,
+		enforcementCounter,
+		templateResolutionFailuresCounter,
+		discoveryFailuresCounter,
+	)
+}
+
+// recordEnforcement counts an enforcement action, unless it is a dry run
+func recordEnforcement(action string, dryRun bool, err error) {
+	if dryRun {
+		return
+	}
+	if err != nil {
+		enforcementCounter.WithLabelValues(action, resultFailure).Inc()
+	} else {
+		enforcementCounter.WithLabelValues(action, resultSuccess).Inc()
+	}
+}
+
+// setComplianceMetric sets a compliance gauge, removing it when the compliance is undetermined
+func setComplianceMetric(gauge *prometheus.GaugeVec, state policyv1.ComplianceState, labels ...string) {
+	switch state {
+	case policyv1.Compliant:
+		gauge.WithLabelValues(labels...).Set(0)
+	case policyv1.NonCompliant:
+		gauge.WithLabelValues(labels...).Set(1)
+	default:
+		gauge.DeleteLabelValues(labels...)
+	}
+}
+
+// updateComplianceMetrics sets the compliance gauges of an evaluated policy and of its templates
+func updateComplianceMetrics(plc *policyv1.ConfigurationPolicy) {
+	namespace, name, severity := plc.GetNamespace(), plc.GetName(), string(plc.Spec.Severity)
+
+	metricPoliciesMx.Lock()
+	defer metricPoliciesMx.Unlock()
+	previous, found := metricPolicies[policyKey(plc)]
+	if found && previous.severity != severity {
+		deleteComplianceMetrics(namespace, name, previous)
+		previous.templates = 0
+	}
+	for i := len(plc.Spec.ObjectTemplates); i < previous.templates; i++ {
+		templateComplianceGauge.DeleteLabelValues(namespace, name, strconv.Itoa(i), severity)
+	}
+	metricPolicies[policyKey(plc)] = complianceMetrics{templates: len(plc.Spec.ObjectTemplates), severity: severity}
+
+	setComplianceMetric(policyComplianceGauge, getComplianceState(plc), namespace, name, severity)
+	for i := range plc.Spec.ObjectTemplates {
+		state := policyv1.ComplianceState("")
+		if i < len(plc.Status.CompliancyDetails) {
+			state = plc.Status.CompliancyDetails[i].ComplianceState
+		}
+		setComplianceMetric(templateComplianceGauge, state, namespace, name, strconv.Itoa(i), severity)
+	}
+}
+
+// deleteComplianceMetrics must be called with the lock held
+func deleteComplianceMetrics(namespace string, name string, set complianceMetrics) {
+	policyComplianceGauge.DeleteLabelValues(namespace, name, set.severity)
+	for i := 0; i < set.templates; i++ {
+		templateComplianceGauge.DeleteLabelValues(namespace, name, strconv.Itoa(i), set.severity)
+	}
+}
+
+// removePolicyMetrics removes the metrics of a deleted policy
+func removePolicyMetrics(namespace string, name string) {
+	evaluationSecondsHistogram.DeleteLabelValues(namespace, name)
+	templateResolutionFailuresCounter.DeleteLabelValues(namespace, name)
+
+	metricPoliciesMx.Lock()
+	defer metricPoliciesMx.Unlock()
+	key := namespace + "/" + name
+	if set, found := metricPolicies[key]; found {
+		deleteComplianceMetrics(namespace, name, set)
+		delete(metricPolicies, key)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"errors"
+	"testing"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestUpdateComplianceMetrics(t *testing.T) {
+	plc := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics-policy", Namespace: "metrics-ns"},
+		Spec: policyv1.ConfigurationPolicySpec{
+			Severity:        policyv1.SeverityHigh,
+			ObjectTemplates: []*policyv1.ObjectTemplate{{}, {}},
+		},
+		Status: policyv1.ConfigurationPolicyStatus{
+			CompliancyDetails: []policyv1.TemplateStatus{
+				{ComplianceState: policyv1.Compliant},
+				{ComplianceState: policyv1.NonCompliant},
+			},
+		},
+	}
+
+	updateComplianceMetrics(plc)
+	assert.Equal(t, float64(1),
+		testutil.ToFloat64(policyComplianceGauge.WithLabelValues("metrics-ns", "metrics-policy", "high")))
+	assert.Equal(t, float64(0),
+		testutil.ToFloat64(templateComplianceGauge.WithLabelValues("metrics-ns", "metrics-policy", "0", "high")))
+	assert.Equal(t, float64(1),
+		testutil.ToFloat64(templateComplianceGauge.WithLabelValues("metrics-ns", "metrics-policy", "1", "high")))
+
+	// the metric of a removed template is removed
+	plc.Spec.ObjectTemplates = plc.Spec.ObjectTemplates[:1]
+	updateComplianceMetrics(plc)
+	assert.Equal(t, float64(0),
+		testutil.ToFloat64(policyComplianceGauge.WithLabelValues("metrics-ns", "metrics-policy", "high")))
+	assert.False(t, templateComplianceGauge.DeleteLabelValues("metrics-ns", "metrics-policy", "1", "high"))
+
+	// the metrics with the previous severity are removed
+	plc.Spec.Severity = policyv1.SeverityCritical
+	updateComplianceMetrics(plc)
+	assert.False(t, policyComplianceGauge.DeleteLabelValues("metrics-ns", "metrics-policy", "high"))
+	assert.False(t, templateComplianceGauge.DeleteLabelValues("metrics-ns", "metrics-policy", "0", "high"))
+	assert.Equal(t, float64(0),
+		testutil.ToFloat64(policyComplianceGauge.WithLabelValues("metrics-ns", "metrics-policy", "critical")))
+
+	removePolicyMetrics("metrics-ns", "metrics-policy")
+	assert.False(t, policyComplianceGauge.DeleteLabelValues("metrics-ns", "metrics-policy", "critical"))
+	assert.False(t, templateComplianceGauge.DeleteLabelValues("metrics-ns", "metrics-policy", "0", "critical"))
+}
+
+func TestRecordEnforcement(t *testing.T) {
+	success := testutil.ToFloat64(enforcementCounter.WithLabelValues(actionCreate, resultSuccess))
+	failure := testutil.ToFloat64(enforcementCounter.WithLabelValues(actionCreate, resultFailure))
+
+	recordEnforcement(actionCreate, false, nil)
+	recordEnforcement(actionCreate, false, errors.New("forbidden"))
+	// dry runs are not counted
+	recordEnforcement(actionCreate, true, nil)
+
+	assert.Equal(t, success+1, testutil.ToFloat64(enforcementCounter.WithLabelValues(actionCreate, resultSuccess)))
+	assert.Equal(t, failure+1, testutil.ToFloat64(enforcementCounter.WithLabelValues(actionCreate, resultFailure)))
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"context"
+	"sort"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/open-cluster-management/config-policy-controller/pkg/common"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// EvaluateObjects evaluates the object templates of a policy against a set of objects, such as rendered
+// manifests, instead of the objects of a cluster. The policy is evaluated like on a cluster holding the objects,
+// as an inform policy, and the compliance of its templates is set in the status of the returned copy.
+// Without a cluster, an object kind is namespaced when a template or one of the objects of that kind sets a
+// namespace, and the templates using template functions can't be resolved and are reported as unknown, like the
+// object-templates-raw of a policy.
+func EvaluateObjects(plc *policyv1.ConfigurationPolicy,
+	objects []unstructured.Unstructured) *policyv1.ConfigurationPolicy {
+	informed := plc.DeepCopy()
+	informed.Status = policyv1.ConfigurationPolicyStatus{}
+	// an inform policy only compares the objects, nothing is created, updated or deleted
+	informed.Spec.RemediationAction = policyv1.Inform
+	apiresourcelist, apigroups := offlineAPIResources(informed, objects)
+	result := evaluatePolicyWith(informed, &evaluationEnv{
+		dclient:        &offlineClient{objects: objects},
+		listNamespaces: offlineNamespaceLister(objects),
+		offline:        true,
+	}, apiresourcelist, apigroups)
+	result.Spec.RemediationAction = plc.Spec.RemediationAction
+	return result
+}
+
+// offlineAPIResources returns the API resources of the kinds of the objects and of the object templates, with
+// their resource names guessed from their kinds. A kind is namespaced when one of its objects or templates sets
+// a namespace.
+func offlineAPIResources(plc *policyv1.ConfigurationPolicy,
+	objects []unstructured.Unstructured) ([]*metav1.APIResourceList, []*restmapper.APIGroupResources) {
+	namespaced := map[schema.GroupVersionKind]bool{}
+	kinds := []schema.GroupVersionKind{}
+	addKind := func(object unstructured.Unstructured) {
+		gvk := object.GroupVersionKind()
+		if gvk.Kind == "" {
+			return
+		}
+		if _, found := namespaced[gvk]; !found {
+			kinds = append(kinds, gvk)
+		}
+		namespaced[gvk] = namespaced[gvk] || object.GetNamespace() != ""
+	}
+	for _, object := range objects {
+		addKind(object)
+	}
+	for _, objectT := range plc.Spec.ObjectTemplates {
+		definition := unstructured.Unstructured{}
+		if err := definition.UnmarshalJSON(objectT.ObjectDefinition.Raw); err == nil {
+			addKind(definition)
+		}
+	}
+
+	apiresourcelist := []*metav1.APIResourceList{}
+	resourceLists := map[schema.GroupVersion]*metav1.APIResourceList{}
+	apigroups := []*restmapper.APIGroupResources{}
+	groups := map[string]*restmapper.APIGroupResources{}
+	for _, gvk := range kinds {
+		rsrc, _ := meta.UnsafeGuessKindToResource(gvk)
+		resource := metav1.APIResource{Name: rsrc.Resource, Kind: gvk.Kind, Namespaced: namespaced[gvk]}
+		gv := gvk.GroupVersion()
+		if resourceLists[gv] == nil {
+			resourceLists[gv] = &metav1.APIResourceList{GroupVersion: gv.String()}
+			apiresourcelist = append(apiresourcelist, resourceLists[gv])
+		}
+		resourceLists[gv].APIResources = append(resourceLists[gv].APIResources, resource)
+
+		group := groups[gv.Group]
+		if group == nil {
+			group = &restmapper.APIGroupResources{
+				Group:              metav1.APIGroup{Name: gv.Group},
+				VersionedResources: map[string][]metav1.APIResource{},
+			}
+			groups[gv.Group] = group
+			apigroups = append(apigroups, group)
+		}
+		if _, found := group.VersionedResources[gv.Version]; !found {
+			version := metav1.GroupVersionForDiscovery{GroupVersion: gv.String(), Version: gv.Version}
+			group.Group.Versions = append(group.Group.Versions, version)
+			if group.Group.PreferredVersion.Version == "" {
+				group.Group.PreferredVersion = version
+			}
+		}
+		group.VersionedResources[gv.Version] = append(group.VersionedResources[gv.Version], resource)
+	}
+	return apiresourcelist, apigroups
+}
+
+// offlineClient is a read-only dynamic client of a set of objects, which are selected by their labels and by any
+// of their fields with matchesFields. A later object replaces the objects with the same name.
+type offlineClient struct {
+	objects []unstructured.Unstructured
+}
+
+// offlineResource reads the objects of a resource, in a namespace when it is set
+type offlineResource struct {
+	objects   []unstructured.Unstructured
+	resource  schema.GroupVersionResource
+	namespace string
+}
+
+func (c *offlineClient) Resource(resource schema.GroupVersionResource) dynamic.NamespaceableResourceInterface {
+	return &offlineResource{objects: c.objects, resource: resource}
+}
+
+func (r *offlineResource) Namespace(namespace string) dynamic.ResourceInterface {
+	return &offlineResource{objects: r.objects, resource: r.resource, namespace: namespace}
+}
+
+// selected returns the objects of the resource by namespace and name
+func (r *offlineResource) selected() map[string]unstructured.Unstructured {
+	selected := map[string]unstructured.Unstructured{}
+	for _, object := range r.objects {
+		rsrc, _ := meta.UnsafeGuessKindToResource(object.GroupVersionKind())
+		if rsrc == r.resource && (r.namespace == "" || object.GetNamespace() == r.namespace) {
+			selected[object.GetNamespace()+"/"+object.GetName()] = object
+		}
+	}
+	return selected
+}
+
+func (r *offlineResource) Get(ctx context.Context, name string, options metav1.GetOptions,
+	subresources ...string) (*unstructured.Unstructured, error) {
+	object, found := r.selected()[r.namespace+"/"+name]
+	if !found || len(subresources) > 0 {
+		return nil, apierrors.NewNotFound(r.resource.GroupResource(), name)
+	}
+	return object.DeepCopy(), nil
+}
+
+func (r *offlineResource) List(ctx context.Context, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	labelSelector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	fieldSelector, err := fields.ParseSelector(opts.FieldSelector)
+	if err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	selected := r.selected()
+	keys := []string{}
+	for key := range selected {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	list := &unstructured.UnstructuredList{}
+	for _, key := range keys {
+		object := selected[key]
+		if labelSelector.Matches(labels.Set(object.GetLabels())) && matchesFields(object, fieldSelector) {
+			list.Items = append(list.Items, *object.DeepCopy())
+		}
+	}
+	return list, nil
+}
+
+// the objects are only read, an inform policy doesn't change them
+
+func (r *offlineResource) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	return nil, apierrors.NewMethodNotSupported(r.resource.GroupResource(), "watch")
+}
+
+func (r *offlineResource) Create(ctx context.Context, obj *unstructured.Unstructured, options metav1.CreateOptions,
+	subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, apierrors.NewMethodNotSupported(r.resource.GroupResource(), "create")
+}
+
+func (r *offlineResource) Update(ctx context.Context, obj *unstructured.Unstructured, options metav1.UpdateOptions,
+	subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, apierrors.NewMethodNotSupported(r.resource.GroupResource(), "update")
+}
+
+func (r *offlineResource) UpdateStatus(ctx context.Context, obj *unstructured.Unstructured,
+	options metav1.UpdateOptions) (*unstructured.Unstructured, error) {
+	return nil, apierrors.NewMethodNotSupported(r.resource.GroupResource(), "update")
+}
+
+func (r *offlineResource) Delete(ctx context.Context, name string, options metav1.DeleteOptions,
+	subresources ...string) error {
+	return apierrors.NewMethodNotSupported(r.resource.GroupResource(), "delete")
+}
+
+func (r *offlineResource) DeleteCollection(ctx context.Context, options metav1.DeleteOptions,
+	listOptions metav1.ListOptions) error {
+	return apierrors.NewMethodNotSupported(r.resource.GroupResource(), "deletecollection")
+}
+
+func (r *offlineResource) Patch(ctx context.Context, name string, pt types.PatchType, data []byte,
+	options metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return nil, apierrors.NewMethodNotSupported(r.resource.GroupResource(), "patch")
+}
+
+// offlineNamespaceLister lists the namespaces matching the selector among the Namespace objects and the
+// namespaces of the other objects, since there is no cluster to list them from
+func offlineNamespaceLister(objects []unstructured.Unstructured) common.NamespaceLister {
+	namespaceLabels := map[string]map[string]string{}
+	for _, object := range objects {
+		if object.GetKind() == "Namespace" && object.GetAPIVersion() == "v1" {
+			namespaceLabels[object.GetName()] = object.GetLabels()
+		} else if _, found := namespaceLabels[object.GetNamespace()]; !found && object.GetNamespace() != "" {
+			namespaceLabels[object.GetNamespace()] = nil
+		}
+	}
+	return func(selector labels.Selector) ([]string, error) {
+		namespaces := []string{}
+		for ns, nsLabels := range namespaceLabels {
+			if selector == nil || selector.Matches(labels.Set(nsLabels)) {
+				namespaces = append(namespaces, ns)
+			}
+		}
+		sort.Strings(namespaces)
+		return namespaces, nil
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"context"
+	"testing"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newOfflineObject(apiVersion string, kind string, name string, namespace string) unstructured.Unstructured {
+	object := unstructured.Unstructured{}
+	object.SetAPIVersion(apiVersion)
+	object.SetKind(kind)
+	object.SetName(name)
+	object.SetNamespace(namespace)
+	return object
+}
+
+func TestOfflineAPIResources(t *testing.T) {
+	objects := []unstructured.Unstructured{
+		newOfflineObject("v1", "Namespace", "default", ""),
+		newOfflineObject("v1", "ConfigMap", "config", "default"),
+		newOfflineObject("apps/v1", "Deployment", "web", "default"),
+		newOfflineObject("apps/v1beta1", "Deployment", "old", "default"),
+	}
+	plc := &policyv1.ConfigurationPolicy{
+		Spec: policyv1.ConfigurationPolicySpec{
+			ObjectTemplates: []*policyv1.ObjectTemplate{
+				// a kind without objects is namespaced when its template sets a namespace
+				{ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "example.com/v1", ` +
+					`"kind": "Widget", "metadata": {"name": "w", "namespace": "default"}}`)}},
+				{ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "example.com/v1", ` +
+					`"kind": "ClusterWidget", "metadata": {"name": "w"}}`)}},
+				{ObjectDefinition: runtime.RawExtension{Raw: []byte(`not json`)}},
+			},
+		},
+	}
+
+	apiresourcelist, apigroups := offlineAPIResources(plc, objects)
+	assert.Equal(t, []*metav1.APIResourceList{
+		{GroupVersion: "v1", APIResources: []metav1.APIResource{
+			{Name: "namespaces", Kind: "Namespace", Namespaced: false},
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+		}},
+		{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{
+			{Name: "deployments", Kind: "Deployment", Namespaced: true},
+		}},
+		{GroupVersion: "apps/v1beta1", APIResources: []metav1.APIResource{
+			{Name: "deployments", Kind: "Deployment", Namespaced: true},
+		}},
+		{GroupVersion: "example.com/v1", APIResources: []metav1.APIResource{
+			{Name: "widgets", Kind: "Widget", Namespaced: true},
+			{Name: "clusterwidgets", Kind: "ClusterWidget", Namespaced: false},
+		}},
+	}, apiresourcelist)
+
+	// the first version of a group is its preferred version
+	assert.Len(t, apigroups, 3)
+	assert.Equal(t, "apps", apigroups[1].Group.Name)
+	assert.Len(t, apigroups[1].Group.Versions, 2)
+	assert.Equal(t, "v1", apigroups[1].Group.PreferredVersion.Version)
+	assert.Len(t, apigroups[1].VersionedResources["v1beta1"], 1)
+}
+
+func TestOfflineNamespaceLister(t *testing.T) {
+	ops := newOfflineObject("v1", "Namespace", "ops", "")
+	ops.SetLabels(map[string]string{"team": "ops"})
+	objects := []unstructured.Unstructured{
+		newOfflineObject("v1", "ConfigMap", "config", "app"),
+		ops,
+		newOfflineObject("v1", "ConfigMap", "config", "ops"),
+	}
+	listNamespaces := offlineNamespaceLister(objects)
+
+	// the namespaces of the objects are listed without the Namespace objects
+	namespaces, err := listNamespaces(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"app", "ops"}, namespaces)
+
+	namespaces, err = listNamespaces(labels.SelectorFromSet(labels.Set{"team": "ops"}))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ops"}, namespaces)
+}
+
+func TestOfflineClient(t *testing.T) {
+	rsrc := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	objects := []unstructured.Unstructured{
+		*newSelectorPod("payments-1", map[string]string{"app": "payments"}, "Pending", ""),
+		*newSelectorPod("payments-2", map[string]string{"app": "payments"}, "Pending", ""),
+		*newSelectorPod("web", map[string]string{"app": "web"}, "Pending", ""),
+		*newSelectorPod("payments-1", map[string]string{"app": "payments"}, "Running", ""),
+		newOfflineObject("v1", "ConfigMap", "web", "default"),
+	}
+	dclient := &offlineClient{objects: objects}
+
+	// a later object replaces the objects with the same name
+	pod, err := dclient.Resource(rsrc).Namespace("default").Get(context.TODO(), "payments-1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	phase, _, _ := unstructured.NestedString(pod.Object, "status", "phase")
+	assert.Equal(t, "Running", phase)
+	_, err = dclient.Resource(rsrc).Namespace("other").Get(context.TODO(), "payments-1", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	// the objects are selected by their labels and fields
+	list, err := dclient.Resource(rsrc).Namespace("default").List(context.TODO(), metav1.ListOptions{
+		LabelSelector: "app=payments",
+		FieldSelector: "status.phase=Pending",
+	})
+	assert.Nil(t, err)
+	assert.Len(t, list.Items, 1)
+	assert.Equal(t, "payments-2", list.Items[0].GetName())
+	list, err = dclient.Resource(rsrc).List(context.TODO(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Len(t, list.Items, 3)
+	_, err = dclient.Resource(rsrc).List(context.TODO(), metav1.ListOptions{FieldSelector: "status.phase"})
+	assert.True(t, apierrors.IsBadRequest(err))
+
+	// the objects are only read
+	_, err = dclient.Resource(rsrc).Namespace("default").Patch(context.TODO(), "web", types.ApplyPatchType,
+		[]byte("{}"), metav1.PatchOptions{})
+	assert.True(t, apierrors.IsMethodNotSupported(err))
+	err = dclient.Resource(rsrc).Namespace("default").Delete(context.TODO(), "web", metav1.DeleteOptions{})
+	assert.True(t, apierrors.IsMethodNotSupported(err))
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"context"
+	"strings"
+
+	"github.com/golang/glog"
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/open-cluster-management/config-policy-controller/pkg/common/templates"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/restmapper"
+)
+
+// pruneFinalizer keeps a deleted policy until the objects it must prune are deleted
+const pruneFinalizer string = "policy.open-cluster-management.io/delete-related-objects"
+
+// shouldPrune returns true when the objects of the policy must be deleted along with it
+func shouldPrune(plc *policyv1.ConfigurationPolicy) bool {
+	return plc.Spec.PruneObjectBehavior == policyv1.PruneDeleteIfCreated ||
+		plc.Spec.PruneObjectBehavior == policyv1.PruneDeleteAll
+}
+
+// hasFinalizer returns true when the finalizer is set on the policy
+func hasFinalizer(plc *policyv1.ConfigurationPolicy, finalizer string) bool {
+	for _, f := range plc.GetFinalizers() {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFinalizer returns the finalizers of the policy without the given one
+func removeFinalizer(plc *policyv1.ConfigurationPolicy, finalizer string) []string {
+	finalizers := []string{}
+	for _, f := range plc.GetFinalizers() {
+		if f != finalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	return finalizers
+}
+
+// getObjectsToPrune returns the related objects to delete along with the policy, objects are only pruned
+// when the policy is enforced and only the objects the policy created unless the behavior is DeleteAll
+func getObjectsToPrune(plc *policyv1.ConfigurationPolicy) []policyv1.RelatedObject {
+	toPrune := []policyv1.RelatedObject{}
+	if strings.ToLower(string(plc.Spec.RemediationAction)) != strings.ToLower(string(policyv1.Enforce)) {
+		return toPrune
+	}
+	for _, object := range plc.Status.RelatedObjects {
+		if object.Properties != nil && object.Properties.CreatedByPolicy {
+			if shouldPrune(plc) {
+				toPrune = append(toPrune, object)
+			}
+			continue
+		}
+		// the objects the policy did not create are the ones it must have and found
+		if plc.Spec.PruneObjectBehavior == policyv1.PruneDeleteAll &&
+			(object.Reason == reasonWantFoundExists || object.Reason == reasonWantFoundNoMatch) {
+			toPrune = append(toPrune, object)
+		}
+	}
+	return toPrune
+}
+
+// pruneObjects deletes the related objects of a deleted policy according to its pruneObjectBehavior
+func pruneObjects(plc *policyv1.ConfigurationPolicy, dclient dynamic.Interface) error {
+	for _, object := range getObjectsToPrune(plc) {
+		gv, err := schema.ParseGroupVersion(object.Object.APIVersion)
+		if err != nil {
+			glog.Errorf("Error parsing the apiVersion of the object %v to prune: %v", object.Object.Metadata.Name, err)
+			continue
+		}
+		// the kind of a related object is the name of its resource
+		rsrc := gv.WithResource(object.Object.Kind)
+		var res dynamic.ResourceInterface = dclient.Resource(rsrc)
+		if object.Object.Metadata.Namespace != "" {
+			res = dclient.Resource(rsrc).Namespace(object.Object.Metadata.Namespace)
+		}
+		deleteOptions := metav1.DeleteOptions{}
+		if object.Properties != nil && object.Properties.UID != "" {
+			// don't delete an object recreated by someone else with the same name
+			uid := types.UID(object.Properties.UID)
+			deleteOptions.Preconditions = &metav1.Preconditions{UID: &uid}
+		}
+		err = res.Delete(context.TODO(), object.Object.Metadata.Name, deleteOptions)
+		if err != nil && !errors.IsNotFound(err) && !errors.IsConflict(err) {
+			glog.Errorf("Error pruning the object `%v` of policy %v: %v", object.Object.Metadata.Name,
+				plc.GetName(), err)
+			return err
+		}
+		glog.V(2).Infof("Pruned %v %v of policy %v", rsrc, object.Object.Metadata.Name, plc.GetName())
+	}
+	return nil
+}
+
+// keepObjectProperties carries the properties of the previous related objects over to the new ones,
+// so that the objects created by the policy are still known after later evaluations
+func keepObjectProperties(related, oldRelated []policyv1.RelatedObject) []policyv1.RelatedObject {
+	for i := range related {
+		if related[i].Properties != nil {
+			continue
+		}
+		for _, old := range oldRelated {
+			if old.Properties != nil && old.Object.APIVersion == related[i].Object.APIVersion &&
+				old.Object.Kind == related[i].Object.Kind &&
+				old.Object.Metadata.Namespace == related[i].Object.Metadata.Namespace &&
+				old.Object.Metadata.Name == related[i].Object.Metadata.Name {
+				related[i].Properties = old.Properties
+			}
+		}
+	}
+	return related
+}
+
+// keepTemplateRelatedObjects adds the previous related objects of an object template that could not be evaluated,
+// so that they and their properties are kept until the template is evaluated again. They are the objects of the
+// kind of the template, with its name when the name is not a template.
+func keepTemplateRelatedObjects(related []policyv1.RelatedObject, objectT *policyv1.ObjectTemplate,
+	apigroups []*restmapper.APIGroupResources, oldRelated []policyv1.RelatedObject) []policyv1.RelatedObject {
+	gvr, ok := getObjectResource(apigroups, objectT.ObjectDefinition.Raw)
+	if !ok {
+		return related
+	}
+	name := ""
+	definition := unstructured.Unstructured{}
+	if err := definition.UnmarshalJSON(objectT.ObjectDefinition.Raw); err == nil &&
+		!templates.HasTemplate(definition.GetName()) {
+		name = definition.GetName()
+	}
+	for _, old := range oldRelated {
+		if old.Object.APIVersion == gvr.GroupVersion().String() && old.Object.Kind == gvr.Resource &&
+			(name == "" || old.Object.Metadata.Name == name) {
+			related = updateRelatedObjectsStatus(related, old)
+		}
+	}
+	return related
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"context"
+	"testing"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newRelatedConfigMap(name string, reason string, properties *policyv1.ObjectProperties) policyv1.RelatedObject {
+	return policyv1.RelatedObject{
+		Object: policyv1.ObjectResource{
+			Kind:       "configmaps",
+			APIVersion: "v1",
+			Metadata:   policyv1.ObjectMetadata{Name: name, Namespace: "default"},
+		},
+		Compliant:  string(policyv1.Compliant),
+		Reason:     reason,
+		Properties: properties,
+	}
+}
+
+func TestGetObjectsToPrune(t *testing.T) {
+	created := newRelatedConfigMap("created", reasonWantFoundExists,
+		&policyv1.ObjectProperties{CreatedByPolicy: true, UID: "1234"})
+	found := newRelatedConfigMap("found", reasonWantFoundNoMatch, nil)
+	unwanted := newRelatedConfigMap("unwanted", reasonWantNotFoundExists, nil)
+	plc := &policyv1.ConfigurationPolicy{
+		Spec: policyv1.ConfigurationPolicySpec{RemediationAction: policyv1.Enforce},
+		Status: policyv1.ConfigurationPolicyStatus{
+			RelatedObjects: []policyv1.RelatedObject{created, found, unwanted},
+		},
+	}
+
+	assert.Empty(t, getObjectsToPrune(plc))
+	plc.Spec.PruneObjectBehavior = policyv1.PruneNone
+	assert.Empty(t, getObjectsToPrune(plc))
+	plc.Spec.PruneObjectBehavior = policyv1.PruneDeleteIfCreated
+	assert.Equal(t, []policyv1.RelatedObject{created}, getObjectsToPrune(plc))
+	plc.Spec.PruneObjectBehavior = policyv1.PruneDeleteAll
+	assert.Equal(t, []policyv1.RelatedObject{created, found}, getObjectsToPrune(plc))
+	// objects of an inform policy are never pruned
+	plc.Spec.RemediationAction = policyv1.Inform
+	assert.Empty(t, getObjectsToPrune(plc))
+}
+
+func TestPruneObjects(t *testing.T) {
+	newConfigMap := func(name string, uid string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   map[string]interface{}{"name": name, "namespace": "default", "uid": uid},
+		}}
+	}
+	dclient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(),
+		newConfigMap("created", "1234"), newConfigMap("found", "5678"))
+	plc := &policyv1.ConfigurationPolicy{
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction:   policyv1.Enforce,
+			PruneObjectBehavior: policyv1.PruneDeleteIfCreated,
+		},
+		Status: policyv1.ConfigurationPolicyStatus{
+			RelatedObjects: []policyv1.RelatedObject{
+				newRelatedConfigMap("created", reasonWantFoundExists,
+					&policyv1.ObjectProperties{CreatedByPolicy: true, UID: "1234"}),
+				newRelatedConfigMap("found", reasonWantFoundExists, nil),
+				// already deleted
+				newRelatedConfigMap("deleted", reasonWantFoundExists,
+					&policyv1.ObjectProperties{CreatedByPolicy: true, UID: "9999"}),
+			},
+		},
+	}
+
+	assert.Nil(t, pruneObjects(plc, dclient))
+	res := dclient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}).Namespace("default")
+	_, err := res.Get(context.TODO(), "created", metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	_, err = res.Get(context.TODO(), "found", metav1.GetOptions{})
+	assert.Nil(t, err)
+}
+
+func TestPruneFinalizer(t *testing.T) {
+	plc := &policyv1.ConfigurationPolicy{}
+	assert.False(t, shouldPrune(plc))
+	plc.Spec.PruneObjectBehavior = policyv1.PruneDeleteAll
+	assert.True(t, shouldPrune(plc))
+
+	plc.SetFinalizers([]string{"other", pruneFinalizer})
+	assert.True(t, hasFinalizer(plc, pruneFinalizer))
+	plc.SetFinalizers(removeFinalizer(plc, pruneFinalizer))
+	assert.False(t, hasFinalizer(plc, pruneFinalizer))
+	assert.Equal(t, []string{"other"}, plc.GetFinalizers())
+}
+
+func TestKeepObjectProperties(t *testing.T) {
+	properties := &policyv1.ObjectProperties{CreatedByPolicy: true, UID: "1234"}
+	oldRelated := []policyv1.RelatedObject{newRelatedConfigMap("created", reasonWantFoundExists, properties)}
+	related := []policyv1.RelatedObject{
+		newRelatedConfigMap("created", reasonWantFoundNoMatch, nil),
+		newRelatedConfigMap("other", reasonWantFoundExists, nil),
+	}
+
+	related = keepObjectProperties(related, oldRelated)
+	assert.Equal(t, properties, related[0].Properties)
+	assert.Nil(t, related[1].Properties)
+}
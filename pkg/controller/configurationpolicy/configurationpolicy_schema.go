@@ -0,0 +1,158 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	openapi_v2 "github.com/googleapis/gnostic/openapiv2"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/kube-openapi/pkg/util/proto"
+)
+
+// listTypes tells whether server-side apply merges the list at the path of the fields of an object item by item,
+// and with which keys, rather than replacing the whole list. The indexes of the lists are not part of the path.
+type listTypes func(path []string) (granular bool, keys []string)
+
+// kindSchemas holds the OpenAPI schemas of the kinds of the cluster, they are loaded on the first enforced update
+// of a resync and loaded again on the next resync, since the CRDs may have changed in the meantime
+var kindSchemas map[schema.GroupVersionKind]proto.Schema
+var kindSchemasLoaded bool
+var kindSchemasMx sync.Mutex
+
+// resetKindSchemas loads the OpenAPI schemas of the kinds again on their next use
+func resetKindSchemas() {
+	kindSchemasMx.Lock()
+	defer kindSchemasMx.Unlock()
+	kindSchemas = nil
+	kindSchemasLoaded = false
+}
+
+// getListTypes returns the list types of a kind from the OpenAPI schema of the cluster, or nil when the schema of
+// the kind is unknown, such as when the controller doesn't run
+func getListTypes(gvk schema.GroupVersionKind) listTypes {
+	kindSchemasMx.Lock()
+	defer kindSchemasMx.Unlock()
+	if !kindSchemasLoaded && clientSet != nil {
+		// the schema is loaded once per resync even when it fails, the whole lists are applied in the meantime
+		kindSchemasLoaded = true
+		document, err := clientSet.Discovery().OpenAPISchema()
+		if err != nil {
+			glog.Errorf("Error loading the OpenAPI schema, the whole lists of the templates are applied: %v", err)
+		} else {
+			kindSchemas, err = indexKindSchemas(document)
+			if err != nil {
+				glog.Errorf("Error parsing the OpenAPI schema, the whole lists of the templates are applied: %v", err)
+			}
+		}
+	}
+	kindSchema, found := kindSchemas[gvk]
+	if !found {
+		return nil
+	}
+	return func(path []string) (bool, []string) {
+		return getListType(kindSchema, path)
+	}
+}
+
+// indexKindSchemas returns the schemas of the kinds of an OpenAPI document
+func indexKindSchemas(document *openapi_v2.Document) (map[schema.GroupVersionKind]proto.Schema, error) {
+	models, err := proto.NewOpenAPIData(document)
+	if err != nil {
+		return nil, err
+	}
+	schemas := map[schema.GroupVersionKind]proto.Schema{}
+	for _, name := range models.ListModels() {
+		model := models.LookupModel(name)
+		gvks, _ := model.GetExtensions()["x-kubernetes-group-version-kind"].([]interface{})
+		for _, gvk := range gvks {
+			schemas[schema.GroupVersionKind{
+				Group:   getExtensionString(gvk, "group"),
+				Version: getExtensionString(gvk, "version"),
+				Kind:    getExtensionString(gvk, "kind"),
+			}] = model
+		}
+	}
+	return schemas, nil
+}
+
+// getExtensionString returns the string value of the key of an extension map, which is parsed from YAML
+func getExtensionString(extension interface{}, key string) string {
+	var value interface{}
+	switch extension := extension.(type) {
+	case map[interface{}]interface{}:
+		value = extension[key]
+	case map[string]interface{}:
+		value = extension[key]
+	}
+	str, _ := value.(string)
+	return str
+}
+
+// resolveSchema returns the schema a reference points to
+func resolveSchema(s proto.Schema) proto.Schema {
+	for {
+		ref, ok := s.(proto.Reference)
+		if !ok {
+			return s
+		}
+		s = ref.SubSchema()
+	}
+}
+
+// getListType returns whether the list at the path of the schema is merged item by item by server-side apply,
+// and the keys of its items. The lists with a map or set list type are merged, the built-in lists without a list
+// type are merged like with a strategic merge patch, with their patch merge key. The other lists are atomic.
+func getListType(kindSchema proto.Schema, path []string) (granular bool, keys []string) {
+	s := kindSchema
+	for _, field := range path {
+		s = resolveSchema(s)
+		// the fields of a list are the fields of its items
+		if array, ok := s.(*proto.Array); ok {
+			s = resolveSchema(array.SubType)
+		}
+		switch typed := s.(type) {
+		case *proto.Kind:
+			s = typed.Fields[field]
+		case *proto.Map:
+			s = typed.SubType
+		default:
+			return false, nil
+		}
+		if s == nil {
+			return false, nil
+		}
+	}
+	array, ok := resolveSchema(s).(*proto.Array)
+	if !ok {
+		return false, nil
+	}
+	extensions := array.GetExtensions()
+	switch extensions["x-kubernetes-list-type"] {
+	case "map":
+		mapKeys, _ := extensions["x-kubernetes-list-map-keys"].([]interface{})
+		for _, key := range mapKeys {
+			if keyStr, ok := key.(string); ok {
+				keys = append(keys, keyStr)
+			}
+		}
+		return true, keys
+	case "set":
+		return true, nil
+	case "atomic":
+		return false, nil
+	}
+	strategy, _ := extensions["x-kubernetes-patch-strategy"].(string)
+	for _, s := range strings.Split(strategy, ",") {
+		if s == "merge" {
+			if key, ok := extensions["x-kubernetes-patch-merge-key"].(string); ok {
+				keys = []string{key}
+			}
+			return true, keys
+		}
+	}
+	return false, nil
+}
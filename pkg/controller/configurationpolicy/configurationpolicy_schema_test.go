@@ -0,0 +1,151 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	prototesting "k8s.io/kube-openapi/pkg/util/proto/testing"
+)
+
+// testSchema has a built-in kind with strategic merge lists and a CRD with list types
+const testSchema = `{
+  "swagger": "2.0",
+  "info": {"title": "Kubernetes", "version": "v1.20.0"},
+  "paths": {},
+  "definitions": {
+    "io.k8s.api.core.v1.Pod": {
+      "type": "object",
+      "properties": {
+        "spec": {"$ref": "#/definitions/io.k8s.api.core.v1.PodSpec"}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "", "kind": "Pod", "version": "v1"}]
+    },
+    "io.k8s.api.core.v1.PodSpec": {
+      "type": "object",
+      "properties": {
+        "containers": {
+          "type": "array",
+          "items": {"$ref": "#/definitions/io.k8s.api.core.v1.Container"},
+          "x-kubernetes-patch-merge-key": "name",
+          "x-kubernetes-patch-strategy": "merge"
+        },
+        "tolerations": {
+          "type": "array",
+          "items": {"$ref": "#/definitions/io.k8s.api.core.v1.Toleration"}
+        }
+      }
+    },
+    "io.k8s.api.core.v1.Container": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "args": {"type": "array", "items": {"type": "string"}},
+        "ports": {
+          "type": "array",
+          "items": {"$ref": "#/definitions/io.k8s.api.core.v1.ContainerPort"},
+          "x-kubernetes-list-map-keys": ["containerPort", "protocol"],
+          "x-kubernetes-list-type": "map",
+          "x-kubernetes-patch-merge-key": "containerPort",
+          "x-kubernetes-patch-strategy": "merge"
+        }
+      }
+    },
+    "io.k8s.api.core.v1.ContainerPort": {
+      "type": "object",
+      "properties": {
+        "containerPort": {"type": "integer"},
+        "protocol": {"type": "string"}
+      }
+    },
+    "io.k8s.api.core.v1.Toleration": {
+      "type": "object",
+      "properties": {
+        "key": {"type": "string"}
+      }
+    },
+    "com.example.v1.Widget": {
+      "type": "object",
+      "properties": {
+        "spec": {
+          "type": "object",
+          "properties": {
+            "items": {
+              "type": "array",
+              "items": {"type": "object", "properties": {"name": {"type": "string"}}},
+              "x-kubernetes-list-map-keys": ["name"],
+              "x-kubernetes-list-type": "map"
+            },
+            "tags": {
+              "type": "array",
+              "items": {"type": "string"},
+              "x-kubernetes-list-type": "set"
+            },
+            "steps": {
+              "type": "array",
+              "items": {"type": "string"},
+              "x-kubernetes-list-type": "atomic"
+            },
+            "values": {"type": "object", "additionalProperties": {"type": "array", "items": {"type": "string"}}}
+          }
+        }
+      },
+      "x-kubernetes-group-version-kind": [{"group": "example.com", "kind": "Widget", "version": "v1"}]
+    }
+  }
+}`
+
+func TestGetListType(t *testing.T) {
+	file, err := ioutil.TempFile("", "openapi-*.json")
+	assert.Nil(t, err)
+	defer os.Remove(file.Name())
+	_, err = file.WriteString(testSchema)
+	assert.Nil(t, err)
+	assert.Nil(t, file.Close())
+	document, err := (&prototesting.Fake{Path: file.Name()}).OpenAPISchema()
+	assert.Nil(t, err)
+	schemas, err := indexKindSchemas(document)
+	assert.Nil(t, err)
+	assert.Len(t, schemas, 2)
+
+	pod := schemas[schema.GroupVersionKind{Version: "v1", Kind: "Pod"}]
+	widget := schemas[schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}]
+	testcases := []struct {
+		kind     string
+		path     []string
+		granular bool
+		keys     []string
+	}{
+		// the built-in lists are merged like with a strategic merge patch
+		{"Pod", []string{"spec", "containers"}, true, []string{"name"}},
+		{"Pod", []string{"spec", "tolerations"}, false, nil},
+		// the fields of the items of a list are in its path
+		{"Pod", []string{"spec", "containers", "args"}, false, nil},
+		{"Pod", []string{"spec", "containers", "ports"}, true, []string{"containerPort", "protocol"}},
+		{"Pod", []string{"spec", "unknown"}, false, nil},
+		{"Pod", []string{"spec"}, false, nil},
+		{"Widget", []string{"spec", "items"}, true, []string{"name"}},
+		{"Widget", []string{"spec", "tags"}, true, nil},
+		{"Widget", []string{"spec", "steps"}, false, nil},
+		{"Widget", []string{"spec", "values", "key"}, false, nil},
+	}
+
+	for _, test := range testcases {
+		kindSchema := pod
+		if test.kind == "Widget" {
+			kindSchema = widget
+		}
+		granular, keys := getListType(kindSchema, test.path)
+		assert.Equal(t, test.granular, granular, "path %v", test.path)
+		assert.Equal(t, test.keys, keys, "path %v", test.path)
+	}
+
+	// the whole lists are applied when the schema is unknown
+	resetKindSchemas()
+	assert.Nil(t, getListTypes(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}))
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"fmt"
+	"strings"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/open-cluster-management/config-policy-controller/pkg/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// getObjectListOptions returns the list options selecting the labels and fields of the objectSelector of an object
+// template on the API server, the owner of the objects is matched with matchesOwner after they are listed
+func getObjectListOptions(selector *policyv1.ObjectSelector) (metav1.ListOptions, error) {
+	listOpts := metav1.ListOptions{}
+	if selector == nil {
+		return listOpts, nil
+	}
+	labelSelector, fieldSelector, err := common.ObjectSelectors(*selector)
+	if err != nil {
+		return listOpts, err
+	}
+	if labelSelector != nil {
+		listOpts.LabelSelector = labelSelector.String()
+	}
+	if fieldSelector != nil {
+		listOpts.FieldSelector = fieldSelector.String()
+	}
+	return listOpts, nil
+}
+
+// matchesOwner returns true when the object has an owner reference to the owner of the objectSelector, or when
+// the selector has no owner
+func matchesOwner(object unstructured.Unstructured, selector *policyv1.ObjectSelector) bool {
+	if selector == nil || selector.Owner == nil {
+		return true
+	}
+	for _, ref := range object.GetOwnerReferences() {
+		if ref.Kind == selector.Owner.Kind && (selector.Owner.Name == "" || ref.Name == selector.Owner.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesFields returns true when the fields of the object match the field selector, for the evaluations without
+// an API server to select them. The fields are read from the object, so any field path with a scalar value can be
+// selected, while the API server rejects the fields its kind does not support.
+func matchesFields(object unstructured.Unstructured, fieldSelector fields.Selector) bool {
+	if fieldSelector == nil || fieldSelector.Empty() {
+		return true
+	}
+	fieldSet := fields.Set{}
+	for _, requirement := range fieldSelector.Requirements() {
+		value, found, err := unstructured.NestedFieldNoCopy(object.Object, strings.Split(requirement.Field, ".")...)
+		if err == nil && found {
+			fieldSet[requirement.Field] = fmt.Sprint(value)
+		}
+	}
+	return fieldSelector.Matches(fieldSet)
+}
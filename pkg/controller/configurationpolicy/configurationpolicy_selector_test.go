@@ -0,0 +1,116 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"testing"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newSelectorPod(name string, labels map[string]string, phase string,
+	owner string) *unstructured.Unstructured {
+	pod := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata":   map[string]interface{}{"name": name, "namespace": "default"},
+		"status":     map[string]interface{}{"phase": phase},
+	}}
+	pod.SetLabels(labels)
+	if owner != "" {
+		pod.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: owner}})
+	}
+	return pod
+}
+
+func TestGetObjectListOptions(t *testing.T) {
+	listOpts, err := getObjectListOptions(nil)
+	assert.Nil(t, err)
+	assert.Equal(t, metav1.ListOptions{}, listOpts)
+
+	listOpts, err = getObjectListOptions(&policyv1.ObjectSelector{
+		MatchLabels:   map[string]string{"app": "payments"},
+		FieldSelector: "status.phase=Running",
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "app=payments", listOpts.LabelSelector)
+	assert.Equal(t, "status.phase=Running", listOpts.FieldSelector)
+
+	_, err = getObjectListOptions(&policyv1.ObjectSelector{FieldSelector: "status.phase"})
+	assert.NotNil(t, err)
+}
+
+func TestGetNamesOfKindSelected(t *testing.T) {
+	dclient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{{Version: "v1", Resource: "pods"}: "PodList"},
+		newSelectorPod("payments-1", map[string]string{"app": "payments"}, "Running", "payments-rs"),
+		newSelectorPod("payments-2", map[string]string{"app": "payments"}, "Running", "other-rs"),
+		newSelectorPod("web", map[string]string{"app": "web"}, "Running", "payments-rs"),
+	)
+	unstruct := unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "Pod"}}
+	selector := &policyv1.ObjectSelector{
+		MatchLabels:   map[string]string{"app": "payments"},
+		FieldSelector: "status.phase=Running",
+		Owner:         &policyv1.OwnerSelector{Kind: "ReplicaSet", Name: "payments-rs"},
+	}
+	listOpts, err := getObjectListOptions(selector)
+	assert.Nil(t, err)
+
+	names, objects, err := getNamesOfKind(unstruct, schema.GroupVersionResource{Version: "v1", Resource: "pods"}, true,
+		"default", dclient, "musthave", false, selector, listOpts)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"payments-1"}, names)
+	assert.Len(t, objects, 1)
+
+	// the labels and fields are selected by the API server
+	actions := dclient.Actions()
+	assert.Len(t, actions, 1)
+	restrictions := actions[0].(clienttesting.ListAction).GetListRestrictions()
+	assert.Equal(t, "app=payments", restrictions.Labels.String())
+	assert.Equal(t, "status.phase=Running", restrictions.Fields.String())
+}
+
+func TestMatchesFields(t *testing.T) {
+	pod := *newSelectorPod("payments-1", map[string]string{"app": "payments"}, "Running", "payments-rs")
+	testcases := []struct {
+		selector string
+		expected bool
+	}{
+		{"", true},
+		{"status.phase=Running,metadata.name=payments-1", true},
+		{"status.phase!=Running", false},
+		{"spec.nodeName=node-1", false},
+	}
+
+	for _, test := range testcases {
+		fieldSelector, err := fields.ParseSelector(test.selector)
+		assert.Nil(t, err)
+		assert.Equal(t, test.expected, matchesFields(pod, fieldSelector), "selector %v", test.selector)
+	}
+}
+
+func TestMatchesOwner(t *testing.T) {
+	pod := *newSelectorPod("payments-1", map[string]string{"app": "payments"}, "Running", "payments-rs")
+	testcases := []struct {
+		selector *policyv1.ObjectSelector
+		expected bool
+	}{
+		{nil, true},
+		{&policyv1.ObjectSelector{Owner: &policyv1.OwnerSelector{Kind: "ReplicaSet"}}, true},
+		{&policyv1.ObjectSelector{Owner: &policyv1.OwnerSelector{Kind: "ReplicaSet", Name: "other-rs"}}, false},
+		{&policyv1.ObjectSelector{Owner: &policyv1.OwnerSelector{Kind: "Job", Name: "payments-rs"}}, false},
+	}
+
+	for _, test := range testcases {
+		assert.Equal(t, test.expected, matchesOwner(pod, test.selector), "selector %+v", test.selector)
+	}
+}
@@ -4,11 +4,15 @@
 package configurationpolicy
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"sort"
 	"strings"
 
 	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/open-cluster-management/config-policy-controller/pkg/common"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
@@ -246,6 +250,383 @@ func formatMetadata(metadata map[string]interface{}) (formatted map[string]inter
 	return md
 }
 
+// getApplyPayload builds the server-side apply configuration of an object template: the identity of the object
+// and the fields set in the template, with their values merged into the existing object. The lists merged item by
+// item according to the list types only have the items of the template, the other lists are applied as a whole
+// with their merged items, so the items musthave doesn't list are kept.
+func getApplyPayload(unstruct unstructured.Unstructured, merged *unstructured.Unstructured,
+	lists listTypes) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"name": merged.GetName(),
+	}
+	if merged.GetNamespace() != "" {
+		metadata["namespace"] = merged.GetNamespace()
+	}
+	payload := map[string]interface{}{
+		"apiVersion": merged.GetAPIVersion(),
+		"kind":       merged.GetKind(),
+		"metadata":   metadata,
+	}
+	for key, val := range unstruct.Object {
+		switch key {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			tmplMetadata, ok := val.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			mergedMetadata := merged.Object["metadata"].(map[string]interface{})
+			for _, field := range []string{"labels", "annotations"} {
+				if tmplField, found := tmplMetadata[field]; found {
+					metadata[field] = getAppliedFields(tmplField, mergedMetadata[field], nil, nil)
+				}
+			}
+		default:
+			payload[key] = getAppliedFields(val, merged.Object[key], []string{key}, lists)
+		}
+	}
+	return payload
+}
+
+// getAppliedFields keeps the fields of the template at the path, with their merged values. The lists merged item
+// by item only keep the merged items which have the fields of an item of the template, so that the field manager
+// doesn't own the other items, when all the items of the template have the keys of the list.
+func getAppliedFields(tmplVal interface{}, mergedVal interface{}, path []string, lists listTypes) interface{} {
+	tmplMap, tmplIsMap := tmplVal.(map[string]interface{})
+	mergedMap, mergedIsMap := mergedVal.(map[string]interface{})
+	if tmplIsMap && mergedIsMap {
+		applied := map[string]interface{}{}
+		for key := range tmplMap {
+			applied[key] = getAppliedFields(tmplMap[key], mergedMap[key], append(path[:len(path):len(path)], key),
+				lists)
+		}
+		return applied
+	}
+	tmplList, tmplIsList := tmplVal.([]interface{})
+	mergedList, mergedIsList := mergedVal.([]interface{})
+	if tmplIsList && mergedIsList && hasListKeys(tmplList, path, lists) {
+		applied := []interface{}{}
+		matched := map[int]bool{}
+		for _, tmplItem := range tmplList {
+			for idx, item := range mergedList {
+				if !matched[idx] && hasFields(tmplItem, item) {
+					matched[idx] = true
+					applied = append(applied, getAppliedFields(tmplItem, item, path, lists))
+					break
+				}
+			}
+		}
+		return applied
+	}
+	if mergedVal == nil {
+		return tmplVal
+	}
+	return mergedVal
+}
+
+// hasListKeys reports whether the list at the path is merged item by item and the items of the template have the
+// keys of its items, which the server needs to merge them
+func hasListKeys(tmplList []interface{}, path []string, lists listTypes) bool {
+	if lists == nil {
+		return false
+	}
+	granular, keys := lists(path)
+	if !granular {
+		return false
+	}
+	for _, item := range tmplList {
+		itemMap, isMap := item.(map[string]interface{})
+		for _, key := range keys {
+			if !isMap || itemMap[key] == nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hasFields reports whether the merged value has the fields of the template value, like the items of the lists
+// matched by musthave
+func hasFields(tmplVal interface{}, mergedVal interface{}) bool {
+	merged, err := mergeSpecs(tmplVal, mergedVal, string(policyv1.MustHave))
+	if err != nil {
+		return false
+	}
+	mergedJSON, err := json.Marshal(merged)
+	if err != nil {
+		return false
+	}
+	valJSON, err := json.Marshal(mergedVal)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(mergedJSON, valJSON)
+}
+
+// maxFieldDifferences limits the number of differences reported for an object
+const maxFieldDifferences = 20
+
+// getObjectDifferences lists the fields of the given keys that differ between the existing object and the
+// desired object, which is the existing object with the template merged into it
+func getObjectDifferences(existing *unstructured.Unstructured, desired *unstructured.Unstructured, keys []string,
+	redact bool) []policyv1.FieldDifference {
+	sort.Strings(keys)
+	differences := []policyv1.FieldDifference{}
+	for _, key := range keys {
+		if key == "metadata" {
+			// only the labels and annotations of the metadata are merged
+			existingMetadata, _ := existing.Object[key].(map[string]interface{})
+			desiredMetadata, _ := desired.Object[key].(map[string]interface{})
+			for _, field := range []string{"annotations", "labels"} {
+				differences = append(differences, getFieldDifferences("metadata."+field,
+					existingMetadata[field], desiredMetadata[field], redact)...)
+			}
+		} else {
+			differences = append(differences, getFieldDifferences(key, existing.Object[key], desired.Object[key],
+				redact)...)
+		}
+	}
+	if len(differences) > maxFieldDifferences {
+		differences = differences[:maxFieldDifferences]
+	}
+	return differences
+}
+
+// getFieldDifferences lists the fields that differ between the actual and expected values, a list is compared
+// as a whole. A missing or null field has no value in the difference, the values are hidden when redact is true.
+func getFieldDifferences(path string, actual interface{}, expected interface{},
+	redact bool) (differences []policyv1.FieldDifference) {
+	actualMap, actualIsMap := actual.(map[string]interface{})
+	expectedMap, expectedIsMap := expected.(map[string]interface{})
+	if actualIsMap && expectedIsMap {
+		keys := []string{}
+		for key := range actualMap {
+			keys = append(keys, key)
+		}
+		for key := range expectedMap {
+			if _, found := actualMap[key]; !found {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			keyPath := key
+			if path != "" {
+				keyPath = path + "." + key
+			}
+			differences = append(differences, getFieldDifferences(keyPath, actualMap[key], expectedMap[key], redact)...)
+		}
+		return differences
+	}
+	if reflect.DeepEqual(actual, expected) {
+		return nil
+	}
+	return []policyv1.FieldDifference{{
+		Path:     path,
+		Expected: formatFieldValue(expected, redact),
+		Actual:   formatFieldValue(actual, redact),
+	}}
+}
+
+// formatFieldValue formats a field value as JSON for a difference, a missing value is empty. The value is hidden
+// when redact is true, and the tracked secret values it contains are redacted otherwise.
+func formatFieldValue(val interface{}, redact bool) string {
+	if val == nil {
+		return ""
+	}
+	if redact {
+		return common.RedactedValue
+	}
+	formatted, err := json.Marshal(val)
+	if err != nil {
+		return common.Redact(fmt.Sprintf("%v", val))
+	}
+	return common.Redact(string(formatted))
+}
+
+// removeForbiddenFields removes from the existing object the fields of a field-level mustnothave template
+// and returns the found fields as differences with no expected value
+func removeForbiddenFields(unstruct unstructured.Unstructured, existingObj *unstructured.Unstructured,
+	redact bool) (remaining *unstructured.Unstructured, differences []policyv1.FieldDifference) {
+	remaining = existingObj.DeepCopy()
+	keys := []string{}
+	for key := range unstruct.Object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "metadata" {
+			// only the labels and annotations of the metadata can be forbidden
+			tmplMetadata, _ := unstruct.Object[key].(map[string]interface{})
+			existingMetadata, _ := remaining.Object[key].(map[string]interface{})
+			for _, field := range []string{"annotations", "labels"} {
+				tmplVal, ok := tmplMetadata[field]
+				if !ok || existingMetadata[field] == nil {
+					continue
+				}
+				kept, removed, found := getForbiddenFields("metadata."+field, tmplVal, existingMetadata[field], redact)
+				differences = append(differences, found...)
+				if removed {
+					delete(existingMetadata, field)
+				} else {
+					existingMetadata[field] = kept
+				}
+			}
+		} else if !isDenylisted(key) {
+			existingVal, ok := remaining.Object[key]
+			if !ok {
+				continue
+			}
+			kept, removed, found := getForbiddenFields(key, unstruct.Object[key], existingVal, redact)
+			differences = append(differences, found...)
+			if removed {
+				delete(remaining.Object, key)
+			} else {
+				remaining.Object[key] = kept
+			}
+		}
+	}
+	if len(differences) > maxFieldDifferences {
+		differences = differences[:maxFieldDifferences]
+	}
+	return remaining, differences
+}
+
+// getForbiddenFields matches a template value against an existing value and returns the existing value
+// without the forbidden fields, or removed when the whole value is forbidden:
+// a null template value forbids the field whatever its value, any other scalar only forbids an equal value,
+// and the keys of a map are matched one by one. An entry of a list forbids the equal entries, if it is a map
+// its scalar fields select the entries it applies to, and its other fields are removed from those entries,
+// or the selected entries are removed when it has no other fields.
+func getForbiddenFields(path string, tmplVal interface{}, existingVal interface{},
+	redact bool) (kept interface{}, removed bool, found []policyv1.FieldDifference) {
+	switch tmplVal := tmplVal.(type) {
+	case nil:
+		return nil, true, []policyv1.FieldDifference{{Path: path, Actual: formatFieldValue(existingVal, redact)}}
+	case map[string]interface{}:
+		existingMap, ok := existingVal.(map[string]interface{})
+		if !ok {
+			return existingVal, false, nil
+		}
+		return removeForbiddenKeys(path, tmplVal, existingMap, redact)
+	case []interface{}:
+		existingList, ok := existingVal.([]interface{})
+		if !ok {
+			return existingVal, false, nil
+		}
+		keptList := []interface{}{}
+		for i, entry := range existingList {
+			entryPath := fmt.Sprintf("%s[%d]", path, i)
+			entryRemoved := false
+			for _, tmplEntry := range tmplVal {
+				var entryFound []policyv1.FieldDifference
+				entry, entryRemoved, entryFound = getForbiddenEntry(entryPath, tmplEntry, entry, redact)
+				found = append(found, entryFound...)
+				if entryRemoved {
+					break
+				}
+			}
+			if !entryRemoved {
+				keptList = append(keptList, entry)
+			}
+		}
+		return keptList, false, found
+	default:
+		if !valuesEqual(tmplVal, existingVal) {
+			return existingVal, false, nil
+		}
+		return nil, true, []policyv1.FieldDifference{{Path: path, Actual: formatFieldValue(existingVal, redact)}}
+	}
+}
+
+// removeForbiddenKeys matches the keys of a template map against an existing map, which is not modified
+func removeForbiddenKeys(path string, tmplMap map[string]interface{}, existingMap map[string]interface{},
+	redact bool) (kept interface{}, removed bool, found []policyv1.FieldDifference) {
+	keys := []string{}
+	for key := range tmplMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	keptMap := map[string]interface{}{}
+	for key, val := range existingMap {
+		keptMap[key] = val
+	}
+	for _, key := range keys {
+		existingVal, ok := keptMap[key]
+		if !ok {
+			continue
+		}
+		keyKept, keyRemoved, keyFound := getForbiddenFields(path+"."+key, tmplMap[key], existingVal, redact)
+		found = append(found, keyFound...)
+		if keyRemoved {
+			delete(keptMap, key)
+		} else {
+			keptMap[key] = keyKept
+		}
+	}
+	return keptMap, false, found
+}
+
+// getForbiddenEntry matches a template list entry against an existing list entry
+func getForbiddenEntry(path string, tmplEntry interface{}, entry interface{},
+	redact bool) (kept interface{}, removed bool, found []policyv1.FieldDifference) {
+	tmplMap, ok := tmplEntry.(map[string]interface{})
+	if !ok {
+		if valuesEqual(tmplEntry, entry) {
+			return nil, true, []policyv1.FieldDifference{{Path: path, Actual: formatFieldValue(entry, redact)}}
+		}
+		return entry, false, nil
+	}
+	entryMap, ok := entry.(map[string]interface{})
+	if !ok {
+		return entry, false, nil
+	}
+	// the scalar fields select the entries, the other ones are removed from them
+	forbidden := map[string]interface{}{}
+	for key, val := range tmplMap {
+		switch val.(type) {
+		case nil, map[string]interface{}, []interface{}:
+			forbidden[key] = val
+		default:
+			if !valuesEqual(val, entryMap[key]) {
+				return entry, false, nil
+			}
+		}
+	}
+	if len(forbidden) == 0 {
+		return nil, true, []policyv1.FieldDifference{{Path: path, Actual: formatFieldValue(entry, redact)}}
+	}
+	return removeForbiddenKeys(path, forbidden, entryMap, redact)
+}
+
+// valuesEqual compares two values by their JSON, since the numbers of the templates and of the objects
+// are not decoded to the same types
+func valuesEqual(val1 interface{}, val2 interface{}) bool {
+	json1, err1 := json.Marshal(val1)
+	json2, err2 := json.Marshal(val2)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return string(json1) == string(json2)
+}
+
+// formatFieldDifferences formats the differences for a message as "path: actual -> expected"
+func formatFieldDifferences(differences []policyv1.FieldDifference) string {
+	formatted := []string{}
+	for _, difference := range differences {
+		actual, expected := difference.Actual, difference.Expected
+		if actual == "" {
+			actual = "<none>"
+		}
+		if expected == "" {
+			expected = "<none>"
+		}
+		formatted = append(formatted, fmt.Sprintf("%s: %s -> %s", difference.Path, actual, expected))
+	}
+	return strings.Join(formatted, "; ")
+}
+
 // Format name of resource with its namespace (if it has one)
 func createResourceNameStr(names []string, namespace string, namespaced bool) (nameStr string) {
 	sort.Strings(names)
@@ -275,8 +656,15 @@ func createResourceNameStr(names []string, namespace string, namespaced bool) (n
 //createMustHaveStatus generates a status for a musthave/mustonlyhave policy
 func createMustHaveStatus(desiredName string, kind string, complianceObjects map[string]map[string]interface{},
 	namespaced bool, plc *policyv1.ConfigurationPolicy, indx int, compliant bool) (update bool) {
+	// the objects of an unnamed template are only listed when they are found but fail the assertions
+	found := false
+	for _, objects := range complianceObjects {
+		if names, ok := objects["names"].([]string); ok && len(names) > 0 {
+			found = true
+		}
+	}
 	// Noncompliant with no resources -- return violation immediately
-	if !compliant && desiredName == "" {
+	if !compliant && desiredName == "" && !found {
 		message := fmt.Sprintf("No instances of `%v` found as specified", kind)
 		return createViolation(plc, indx, "K8s does not have a `must have` object", message)
 	}
@@ -296,6 +684,8 @@ func createMustHaveStatus(desiredName string, kind string, complianceObjects map
 		} else {
 			if complianceObjects[ns]["reason"] == reasonWantFoundNoMatch {
 				nameStr += " found but not as specified"
+			} else if complianceObjects[ns]["reason"] == reasonAssertionsFailed {
+				nameStr += " found but failed the assertions"
 			} else {
 				nameStr += " missing"
 			}
@@ -342,3 +732,32 @@ func createMustNotHaveStatus(kind string, complianceObjects map[string]map[strin
 	message := fmt.Sprintf("%v found: %v", kind, names)
 	return createViolation(plc, indx, "K8s has a `must not have` object", message)
 }
+
+//createMustNotHaveFieldsStatus generates a status for a field-level mustnothave policy
+func createMustNotHaveFieldsStatus(kind string, complianceObjects map[string]map[string]interface{},
+	namespaced bool, plc *policyv1.ConfigurationPolicy, indx int, compliant bool) (update bool) {
+	nameList := []string{}
+	sortedNamespaces := []string{}
+	for n := range complianceObjects {
+		sortedNamespaces = append(sortedNamespaces, n)
+	}
+	sort.Strings(sortedNamespaces)
+	for i := range sortedNamespaces {
+		ns := sortedNamespaces[i]
+		names := complianceObjects[ns]["names"].([]string)
+		nameStr := createResourceNameStr(names, ns, namespaced)
+		if !stringInSlice(nameStr, nameList) {
+			nameList = append(nameList, nameStr)
+		}
+	}
+	names := strings.Join(nameList, "; ")
+	// Compliant -- return notification
+	if compliant {
+		message := fmt.Sprintf("%v %v without the forbidden fields, therefore this Object template is compliant",
+			kind, names)
+		return createNotification(plc, indx, "K8s `must not have` fields already missing", message)
+	}
+	// Noncompliant -- return violation
+	message := fmt.Sprintf("%v with forbidden fields found: %v", kind, names)
+	return createViolation(plc, indx, "K8s has `must not have` fields", message)
+}
@@ -0,0 +1,262 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"sync"
+
+	"github.com/golang/glog"
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/cache"
+)
+
+// objWatcher reports the policies to re-evaluate when an object they refer to changes,
+// it is nil when watching the resources is disabled
+var objWatcher *objectWatcher
+
+// maxNamespaceWatches is the number of namespaces of an object template above which its resource is watched in all
+// the namespaces with a single informer, rather than with an informer per namespace
+const maxNamespaceWatches = 10
+
+// namespacesResource is watched for the object templates selecting their namespaces, since the objects of the new
+// namespaces are not watched until the policy is evaluated again
+var namespacesResource = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// watchScope is a set of watched objects: the objects of a resource, in a namespace unless it is empty, and with
+// a name unless it is empty
+type watchScope struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+}
+
+// objectWatcher runs metadata informers on the objects referred to by the object templates of the policies, in
+// their namespaces and with their names when they have some, the policies share the informers of the same objects.
+// Only the object metadata is cached, since the informers are only used to know when something changed, still the
+// informers watching all the objects of a resource cache the metadata of all of them.
+type objectWatcher struct {
+	client metadata.Interface
+	lock   sync.Mutex
+	// informers holds the stop channel of the informer running for each watched scope
+	informers map[watchScope]chan struct{}
+	// synced holds the function reporting whether the informer of each watched scope listed its objects
+	synced map[watchScope]cache.InformerSynced
+	// policies holds the scopes watched for each policy key
+	policies map[string]map[watchScope]bool
+	// pending holds the keys of the policies to evaluate
+	pending map[string]bool
+	// notify receives a value when a policy is added to pending
+	notify chan struct{}
+}
+
+// InitializeObjectWatcher enables evaluating the policies when an object they refer to changes,
+// rather than only on the periodic resync
+func InitializeObjectWatcher(kubeconfig *rest.Config) error {
+	client, err := metadata.NewForConfig(kubeconfig)
+	if err != nil {
+		return err
+	}
+	objWatcher = newObjectWatcher(client)
+	return nil
+}
+
+func newObjectWatcher(client metadata.Interface) *objectWatcher {
+	return &objectWatcher{
+		client:    client,
+		informers: map[watchScope]chan struct{}{},
+		synced:    map[watchScope]cache.InformerSynced{},
+		policies:  map[string]map[watchScope]bool{},
+		pending:   map[string]bool{},
+		notify:    make(chan struct{}, 1),
+	}
+}
+
+// policyKey returns the key identifying a policy in the object watcher
+func policyKey(plc *policyv1.ConfigurationPolicy) string {
+	return plc.GetNamespace() + "/" + plc.GetName()
+}
+
+// watchResources sets the scopes watched for a policy, starting the informers of the new scopes
+// and stopping the ones no policy refers to anymore
+func (w *objectWatcher) watchResources(key string, scopes []watchScope) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if len(scopes) == 0 {
+		delete(w.policies, key)
+	} else {
+		watched := map[watchScope]bool{}
+		for _, scope := range scopes {
+			watched[scope] = true
+		}
+		w.policies[key] = watched
+	}
+
+	used := map[watchScope]bool{}
+	for _, watched := range w.policies {
+		for scope := range watched {
+			used[scope] = true
+		}
+	}
+	for scope := range used {
+		if _, ok := w.informers[scope]; !ok {
+			w.startInformer(scope)
+		}
+	}
+	for scope, stop := range w.informers {
+		if !used[scope] {
+			glog.V(2).Infof("stopping the watch on %v", scope)
+			close(stop)
+			delete(w.informers, scope)
+			delete(w.synced, scope)
+		}
+	}
+}
+
+// removePolicy stops watching the resources of a deleted policy
+func (w *objectWatcher) removePolicy(key string) {
+	w.watchResources(key, nil)
+	w.lock.Lock()
+	delete(w.pending, key)
+	w.lock.Unlock()
+}
+
+// startInformer must be called with the lock held
+func (w *objectWatcher) startInformer(scope watchScope) {
+	glog.V(2).Infof("starting a watch on %v", scope)
+	var tweakListOptions metadatainformer.TweakListOptionsFunc
+	if scope.name != "" {
+		tweakListOptions = func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", scope.name).String()
+		}
+	}
+	informer := metadatainformer.NewFilteredMetadataInformer(w.client, scope.gvr, scope.namespace, 0,
+		cache.Indexers{}, tweakListOptions).Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			// the initial list of the objects is not a change, most of it is ignored here
+			if informer.HasSynced() {
+				w.resourceChanged(scope)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			w.resourceChanged(scope)
+		},
+		DeleteFunc: func(obj interface{}) {
+			w.resourceChanged(scope)
+		},
+	})
+	stop := make(chan struct{})
+	w.informers[scope] = stop
+	w.synced[scope] = informer.HasSynced
+	go informer.Run(stop)
+}
+
+// hasSynced returns true when the informers of all the watched resources listed their objects
+func (w *objectWatcher) hasSynced() bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for _, synced := range w.synced {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+// resourceChanged queues the policies watching the scope for evaluation
+func (w *objectWatcher) resourceChanged(scope watchScope) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	for key, watched := range w.policies {
+		if watched[scope] {
+			w.queue(key)
+		}
+	}
+}
+
+// trigger queues a policy for evaluation
+func (w *objectWatcher) trigger(key string) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.queue(key)
+}
+
+// queue must be called with the lock held
+func (w *objectWatcher) queue(key string) {
+	w.pending[key] = true
+	select {
+	case w.notify <- struct{}{}:
+	default:
+		// a notification is already waiting to be read
+	}
+}
+
+// popPending returns the keys of the policies to evaluate and clears them
+func (w *objectWatcher) popPending() []string {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	keys := []string{}
+	for key := range w.pending {
+		keys = append(keys, key)
+	}
+	w.pending = map[string]bool{}
+	return keys
+}
+
+// getObjectMapping returns the mapping of an object definition, ok is false if it can't be mapped
+func getObjectMapping(apigroups []*restmapper.APIGroupResources, raw []byte) (*meta.RESTMapping, bool) {
+	_, gvk, err := unstructured.UnstructuredJSONScheme.Decode(raw, nil, nil)
+	if err != nil {
+		return nil, false
+	}
+	mapping, err := restmapper.NewDiscoveryRESTMapper(apigroups).RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, false
+	}
+	return mapping, true
+}
+
+// getObjectResource returns the resource of an object definition, ok is false if it can't be mapped
+func getObjectResource(apigroups []*restmapper.APIGroupResources,
+	raw []byte) (gvr schema.GroupVersionResource, ok bool) {
+	mapping, ok := getObjectMapping(apigroups, raw)
+	if !ok {
+		return gvr, false
+	}
+	return mapping.Resource, true
+}
+
+// getWatchScopes returns the scopes watched for an object definition: its objects with its name when it has one,
+// in each of its namespaces when its kind is namespaced, or in all the namespaces when it has more than
+// maxNamespaceWatches of them. The namespaces are also watched when they are selected, so that the objects of the
+// new namespaces are evaluated.
+func getWatchScopes(apigroups []*restmapper.APIGroupResources, raw []byte, namespaces []string, selected bool,
+	name string) []watchScope {
+	mapping, ok := getObjectMapping(apigroups, raw)
+	if !ok {
+		return nil
+	}
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace || len(namespaces) == 0 ||
+		len(namespaces) > maxNamespaceWatches {
+		return []watchScope{{gvr: mapping.Resource, name: name}}
+	}
+	scopes := []watchScope{}
+	for _, ns := range namespaces {
+		scopes = append(scopes, watchScope{gvr: mapping.Resource, namespace: ns, name: name})
+	}
+	if selected {
+		scopes = append(scopes, watchScope{gvr: namespacesResource})
+	}
+	return scopes
+}
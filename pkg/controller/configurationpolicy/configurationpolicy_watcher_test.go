@@ -0,0 +1,129 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package configurationpolicy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/restmapper"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestObjectWatcher(t *testing.T) {
+	cmGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	podGVR := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	newObj := func(name string) *metav1.PartialObjectMetadata {
+		return &metav1.PartialObjectMetadata{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+		}
+	}
+	scheme := runtime.NewScheme()
+	assert.Nil(t, metav1.AddMetaToScheme(scheme))
+	client := metadatafake.NewSimpleMetadataClient(scheme, newObj("existing"))
+	w := newObjectWatcher(client)
+
+	w.watchResources("default/cm-policy", []watchScope{{gvr: cmGVR, namespace: "default"}})
+	w.watchResources("default/pod-policy", []watchScope{{gvr: podGVR, namespace: "default"}})
+	// the policies watching the same objects share their informer
+	w.watchResources("default/other-policy", []watchScope{{gvr: podGVR, namespace: "default"}})
+	assert.Len(t, w.informers, 2)
+
+	// the objects created after the initial list of the informers are changes
+	assert.Eventually(t, w.hasSynced, 5*time.Second, 10*time.Millisecond)
+
+	_, err := client.Resource(cmGVR).Namespace("default").(metadatafake.MetadataClient).CreateFake(
+		newObj("created"), metav1.CreateOptions{})
+	assert.Nil(t, err)
+	select {
+	case <-w.notify:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the policy was not queued after the object was created")
+	}
+	assert.Equal(t, []string{"default/cm-policy"}, w.popPending())
+
+	err = client.Resource(cmGVR).Namespace("default").Delete(context.TODO(), "existing", metav1.DeleteOptions{})
+	assert.Nil(t, err)
+	select {
+	case <-w.notify:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the policy was not queued after the object was deleted")
+	}
+	assert.Equal(t, []string{"default/cm-policy"}, w.popPending())
+
+	// the informer of a scope stops when no policy refers to it anymore
+	w.removePolicy("default/pod-policy")
+	assert.Len(t, w.informers, 2)
+	w.removePolicy("default/other-policy")
+	assert.Len(t, w.informers, 1)
+	w.watchResources("default/cm-policy", nil)
+	assert.Empty(t, w.informers)
+	assert.Empty(t, w.policies)
+
+	w.trigger("default/cm-policy")
+	assert.Equal(t, []string{"default/cm-policy"}, w.popPending())
+}
+
+func TestObjectWatcherScopes(t *testing.T) {
+	cmGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	scheme := runtime.NewScheme()
+	assert.Nil(t, metav1.AddMetaToScheme(scheme))
+	client := metadatafake.NewSimpleMetadataClient(scheme)
+	w := newObjectWatcher(client)
+	defer w.watchResources("default/cm-policy", nil)
+
+	// the informers only list and watch the objects of their namespace and name
+	w.watchResources("default/cm-policy", []watchScope{{gvr: cmGVR, namespace: "default", name: "foo"}})
+	assert.Eventually(t, w.hasSynced, 5*time.Second, 10*time.Millisecond)
+	listed := false
+	for _, action := range client.Actions() {
+		if list, ok := action.(clienttesting.ListAction); ok {
+			listed = true
+			assert.Equal(t, "default", list.GetNamespace())
+			assert.Equal(t, "metadata.name=foo", list.GetListRestrictions().Fields.String())
+		}
+	}
+	assert.True(t, listed)
+}
+
+func TestGetWatchScopes(t *testing.T) {
+	cmGVR := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	nsGVR := schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	apigroups := []*restmapper.APIGroupResources{{
+		Group: metav1.APIGroup{
+			Versions:         []metav1.GroupVersionForDiscovery{{GroupVersion: "v1", Version: "v1"}},
+			PreferredVersion: metav1.GroupVersionForDiscovery{GroupVersion: "v1", Version: "v1"},
+		},
+		VersionedResources: map[string][]metav1.APIResource{"v1": {
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+			{Name: "namespaces", Kind: "Namespace", Namespaced: false},
+		}},
+	}}
+	configMap := []byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "foo"}}`)
+	namespace := []byte(`{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "foo"}}`)
+	manyNamespaces := []string{}
+	for i := 0; i <= maxNamespaceWatches; i++ {
+		manyNamespaces = append(manyNamespaces, fmt.Sprintf("ns-%d", i))
+	}
+
+	assert.Equal(t, []watchScope{{gvr: cmGVR, namespace: "default", name: "foo"}},
+		getWatchScopes(apigroups, configMap, []string{"default"}, false, "foo"))
+	// the selected namespaces are watched for the new namespaces
+	assert.Equal(t, []watchScope{
+		{gvr: cmGVR, namespace: "app-1"}, {gvr: cmGVR, namespace: "app-2"}, {gvr: nsGVR},
+	}, getWatchScopes(apigroups, configMap, []string{"app-1", "app-2"}, true, ""))
+	assert.Equal(t, []watchScope{{gvr: cmGVR, name: "foo"}},
+		getWatchScopes(apigroups, configMap, manyNamespaces, true, "foo"))
+	assert.Equal(t, []watchScope{{gvr: nsGVR, name: "foo"}},
+		getWatchScopes(apigroups, namespace, []string{"default"}, true, "foo"))
+	assert.Nil(t, getWatchScopes(apigroups, []byte(`{"apiVersion": "v1", "kind": "Unknown"}`), nil, true, ""))
+}
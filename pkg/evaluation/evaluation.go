@@ -0,0 +1,130 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+// Package evaluation evaluates ConfigurationPolicies with the semantics of the controller, without running it,
+// so that other controllers and tools can check the compliance of their own policies.
+package evaluation
+
+import (
+	"context"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/open-cluster-management/config-policy-controller/pkg/common"
+	"github.com/open-cluster-management/config-policy-controller/pkg/controller/configurationpolicy"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+)
+
+// ClusterAccess holds the clients a policy is evaluated with
+type ClusterAccess struct {
+	// Dynamic reads the objects of the object templates and the namespaces, and changes the objects of the
+	// enforced policies. The lookup and fromClusterClaim template functions also read with it.
+	Dynamic dynamic.Interface
+	// Discovery maps the kinds of the object templates to their resources
+	Discovery discovery.DiscoveryInterface
+	// Kube reads the Secrets and ConfigMaps of the fromSecret and fromConfigMap template functions, they fail
+	// when it is not set
+	Kube kubernetes.Interface
+}
+
+// TemplateResult is the compliance of an object template, with its current condition
+type TemplateResult struct {
+	Index           int                      `json:"index"`
+	ComplianceState policyv1.ComplianceState `json:"compliant"`
+	Reason          string                   `json:"reason,omitempty"`
+	Message         string                   `json:"message,omitempty"`
+}
+
+// Result is the compliance of an evaluated policy and of its object templates
+type Result struct {
+	Namespace       string                   `json:"namespace,omitempty"`
+	Name            string                   `json:"name"`
+	ComplianceState policyv1.ComplianceState `json:"compliant"`
+	Templates       []TemplateResult         `json:"templates"`
+	RelatedObjects  []policyv1.RelatedObject `json:"relatedObjects,omitempty"`
+	// Status is the status the controller would set on the policy, with the history of each template
+	Status policyv1.ConfigurationPolicyStatus `json:"-"`
+}
+
+// NewResult returns the result of an evaluated policy from its status
+func NewResult(plc *policyv1.ConfigurationPolicy) Result {
+	result := Result{
+		Namespace:       plc.GetNamespace(),
+		Name:            plc.GetName(),
+		ComplianceState: plc.Status.ComplianceState,
+		Templates:       []TemplateResult{},
+		RelatedObjects:  plc.Status.RelatedObjects,
+		Status:          plc.Status,
+	}
+	for i, details := range plc.Status.CompliancyDetails {
+		template := TemplateResult{Index: i, ComplianceState: details.ComplianceState}
+		if len(details.Conditions) > 0 {
+			template.Reason = details.Conditions[0].Reason
+			template.Message = details.Conditions[0].Message
+		}
+		result.Templates = append(result.Templates, template)
+	}
+	return result
+}
+
+// Evaluate evaluates a policy on the cluster of the clients, the policy is not modified and its status is not
+// updated on the cluster. The status of the policy is the previous status, so that the conditions and the
+// history of its templates carry over. Like in the controller, an enforced policy creates, updates and deletes
+// objects, use the inform remediationAction to only check them. The templates of the policy read from the
+// cluster of the clients.
+func Evaluate(ctx context.Context, plc *policyv1.ConfigurationPolicy, access ClusterAccess) (Result, error) {
+	_, apiresourcelist, err := access.Discovery.ServerGroupsAndResources()
+	if err != nil {
+		return Result{}, err
+	}
+	apigroups, err := restmapper.GetAPIGroupResources(access.Discovery)
+	if err != nil {
+		return Result{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return Result{}, err
+	}
+	evaluated := configurationpolicy.EvaluatePolicy(plc, access.Dynamic, access.Kube,
+		namespaceLister(ctx, access.Dynamic), apiresourcelist, apigroups)
+	return NewResult(evaluated), nil
+}
+
+// ExpireSecretValues forgets the secret values read by the evaluations that were not read again since the previous
+// call. The values read from Secrets by the templates are redacted from the results of all the evaluations, and
+// are kept until they expire, so callers evaluating their policies repeatedly call it before evaluating all of
+// them again.
+func ExpireSecretValues() {
+	common.ExpireSecretValues()
+}
+
+// EvaluateObjects evaluates a policy against the given objects instead of the objects of a cluster, see
+// configurationpolicy.EvaluateObjects
+func EvaluateObjects(plc *policyv1.ConfigurationPolicy, objects []unstructured.Unstructured) Result {
+	return NewResult(configurationpolicy.EvaluateObjects(plc, objects))
+}
+
+// namespaceLister lists the namespaces with the dynamic client
+func namespaceLister(ctx context.Context, dclient dynamic.Interface) common.NamespaceLister {
+	return func(selector labels.Selector) ([]string, error) {
+		listOpts := metav1.ListOptions{}
+		if selector != nil {
+			listOpts.LabelSelector = selector.String()
+		}
+		namespaceList, err := dclient.Resource(schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}).
+			List(ctx, listOpts)
+		if err != nil {
+			return []string{}, err
+		}
+		names := []string{}
+		for _, ns := range namespaceList.Items {
+			names = append(names, ns.GetName())
+		}
+		return names, nil
+	}
+}
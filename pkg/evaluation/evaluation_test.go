@@ -0,0 +1,657 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package evaluation
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newClusterAccess(objects ...runtime.Object) ClusterAccess {
+	dclient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			{Version: "v1", Resource: "namespaces"}: "NamespaceList",
+			{Version: "v1", Resource: "configmaps"}: "ConfigMapList",
+		}, objects...)
+	discovery := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+	discovery.Resources = []*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "namespaces", Kind: "Namespace", Namespaced: false},
+			{Name: "configmaps", Kind: "ConfigMap", Namespaced: true},
+		},
+	}}
+	return ClusterAccess{Dynamic: dclient, Discovery: discovery}
+}
+
+func newObject(kind string, name string, namespace string, data map[string]interface{}) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       kind,
+		"metadata":   map[string]interface{}{"name": name},
+	}}
+	if namespace != "" {
+		object.SetNamespace(namespace)
+	}
+	if data != nil {
+		object.Object["data"] = data
+	}
+	return object
+}
+
+func TestEvaluate(t *testing.T) {
+	access := newClusterAccess(
+		newObject("Namespace", "default", "", nil),
+		newObject("Namespace", "other", "", nil),
+		newObject("ConfigMap", "config", "default", map[string]interface{}{"key": "value"}),
+		newObject("ConfigMap", "config", "other", map[string]interface{}{"key": "other"}),
+	)
+	plc := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy-configmap", Namespace: "managed"},
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Inform,
+			NamespaceSelector: policyv1.Target{Include: []string{"*"}},
+			ObjectTemplates: []*policyv1.ObjectTemplate{{
+				ComplianceType: policyv1.MustHave,
+				ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+					`"metadata": {"name": "config"}, "data": {"key": "value"}}`)},
+			}},
+		},
+	}
+
+	result, err := Evaluate(context.TODO(), plc, access)
+	assert.Nil(t, err)
+	assert.Empty(t, plc.Status.CompliancyDetails, "the policy must not be modified")
+	assert.Equal(t, "policy-configmap", result.Name)
+	assert.Equal(t, policyv1.NonCompliant, result.ComplianceState)
+	assert.Len(t, result.Templates, 1)
+	assert.Equal(t, policyv1.NonCompliant, result.Templates[0].ComplianceState)
+	assert.Equal(t, "configmaps not found: [config] in namespace other found but not as specified",
+		result.Templates[0].Message)
+	assert.Len(t, result.RelatedObjects, 2)
+	assert.Equal(t, result.Status.CompliancyDetails[0].ComplianceState, result.Templates[0].ComplianceState)
+
+	// only the namespace of the matching object is selected
+	plc.Spec.NamespaceSelector.Include = []string{"default"}
+	result, err = Evaluate(context.TODO(), plc, access)
+	assert.Nil(t, err)
+	assert.Equal(t, policyv1.Compliant, result.ComplianceState)
+}
+
+func TestEvaluateCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	_, err := Evaluate(ctx, &policyv1.ConfigurationPolicy{}, newClusterAccess())
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestEvaluateTemplateError(t *testing.T) {
+	access := newClusterAccess(
+		newObject("Namespace", "default", "", nil),
+		newObject("ConfigMap", "config", "default", map[string]interface{}{"key": "value"}),
+	)
+	plc := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy-template-error", Namespace: "managed"},
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Inform,
+			ObjectTemplates: []*policyv1.ObjectTemplate{
+				{
+					ComplianceType: policyv1.MustHave,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+						`"metadata": {"name": "config", "namespace": "default"}, "data": {"key": "{{ blah }}"}}`)},
+				},
+				{
+					ComplianceType: policyv1.MustHave,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+						`"metadata": {"name": "config", "namespace": "default"}, "data": {"key": "value"}}`)},
+				},
+			},
+		},
+	}
+
+	// the broken template is reported on its own index and does not hide the result of the other template
+	result, err := Evaluate(context.TODO(), plc, access)
+	assert.Nil(t, err)
+	assert.Equal(t, policyv1.NonCompliant, result.ComplianceState)
+	assert.Len(t, result.Templates, 2)
+	assert.Equal(t, policyv1.NonCompliant, result.Templates[0].ComplianceState)
+	assert.Equal(t, "Error processing template", result.Templates[0].Reason)
+	assert.Equal(t, `template error in function blah at {{ blah }}: function "blah" not defined`,
+		result.Templates[0].Message)
+	assert.Equal(t, policyv1.Compliant, result.Templates[1].ComplianceState)
+}
+
+func TestEvaluateTemplateClients(t *testing.T) {
+	access := newClusterAccess(
+		newObject("Namespace", "default", "", nil),
+		newObject("ConfigMap", "config", "default", map[string]interface{}{"key": "value"}),
+		newObject("ConfigMap", "source", "default", map[string]interface{}{"key": "value"}),
+	)
+	plc := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy-template-clients", Namespace: "default"},
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Inform,
+			ObjectTemplates: []*policyv1.ObjectTemplate{
+				{
+					ComplianceType: policyv1.MustHave,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+						`"metadata": {"name": "config", "namespace": "default"},` +
+						`"data": {"key": "{{ fromConfigMap \"default\" \"source\" \"key\" }}"}}`)},
+				},
+				{
+					ComplianceType: policyv1.MustHave,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+						`"metadata": {"name": "config", "namespace": "default"}, "data": {"key": ` +
+						`"{{ (lookup \"v1\" \"ConfigMap\" \"default\" \"source\").data.key }}"}}`)},
+				},
+			},
+		},
+	}
+
+	// fromConfigMap can't read without the Kubernetes client, lookup reads with the dynamic client
+	result, err := Evaluate(context.TODO(), plc, access)
+	assert.Nil(t, err)
+	assert.Len(t, result.Templates, 2)
+	assert.Equal(t, "Error processing template", result.Templates[0].Reason)
+	assert.Equal(t, `template error in function fromConfigMap at {{ fromConfigMap "default" "source" "key" }}: `+
+		"fromConfigMap needs a Kubernetes client, none was set to resolve the templates", result.Templates[0].Message)
+	assert.Equal(t, policyv1.Compliant, result.Templates[1].ComplianceState)
+
+	access.Kube = fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "source", Namespace: "default"},
+		Data:       map[string]string{"key": "value"},
+	})
+	result, err = Evaluate(context.TODO(), plc, access)
+	assert.Nil(t, err)
+	assert.Equal(t, policyv1.Compliant, result.ComplianceState)
+}
+
+func TestEvaluateObjectSelectorListError(t *testing.T) {
+	access := newClusterAccess(
+		newObject("Namespace", "default", "", nil),
+		newObject("ConfigMap", "config", "default", map[string]interface{}{"key": "value"}),
+	)
+	access.Dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("list", "configmaps",
+		func(action clienttesting.Action) (bool, runtime.Object, error) {
+			return true, nil, errors.New(`field label not supported: data.key`)
+		})
+	plc := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy-selector", Namespace: "managed"},
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Inform,
+			ObjectTemplates: []*policyv1.ObjectTemplate{{
+				ComplianceType: policyv1.MustNotHave,
+				ObjectSelector: &policyv1.ObjectSelector{FieldSelector: "data.key=value"},
+				ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+					`"metadata": {"namespace": "default"}}`)},
+			}},
+		},
+	}
+
+	// the objects that can't be listed are not reported as missing
+	result, err := Evaluate(context.TODO(), plc, access)
+	assert.Nil(t, err)
+	assert.Equal(t, policyv1.NonCompliant, result.ComplianceState)
+	assert.Equal(t, "Invalid object selector", result.Templates[0].Reason)
+	assert.Equal(t, "Error selecting the objects, please check the objectSelector: field label not supported: "+
+		"data.key", result.Templates[0].Message)
+}
+
+func TestEvaluateAssertionsOfListedObjects(t *testing.T) {
+	access := newClusterAccess(
+		newObject("Namespace", "default", "", nil),
+		newObject("ConfigMap", "config", "default", map[string]interface{}{"key": "value"}),
+		newObject("ConfigMap", "other", "default", map[string]interface{}{"key": "other"}),
+	)
+	plc := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy-assertions", Namespace: "managed"},
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Inform,
+			ObjectTemplates: []*policyv1.ObjectTemplate{{
+				ComplianceType: policyv1.MustHave,
+				Assertions: []policyv1.Assertion{
+					{Path: ".data.key", Operator: policyv1.AssertEquals, Value: "value"},
+				},
+				ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+					`"metadata": {"namespace": "default"}}`)},
+			}},
+		},
+	}
+
+	// the assertions of the listed objects are checked without getting them again
+	result, err := Evaluate(context.TODO(), plc, access)
+	assert.Nil(t, err)
+	assert.Equal(t, policyv1.NonCompliant, result.ComplianceState)
+	assert.Len(t, result.RelatedObjects, 2)
+	for _, related := range result.RelatedObjects {
+		if related.Object.Metadata.Name == "other" {
+			assert.Equal(t, []policyv1.AssertionFailure{{Path: "data.key", Operator: policyv1.AssertEquals,
+				Value: "value", Actual: `"other"`}}, related.FailedAssertions)
+		} else {
+			assert.Empty(t, related.FailedAssertions)
+		}
+	}
+	for _, action := range access.Dynamic.(*dynamicfake.FakeDynamicClient).Actions() {
+		assert.NotEqual(t, "get", action.GetVerb())
+	}
+}
+
+func TestEvaluateObjectTemplatesRaw(t *testing.T) {
+	access := newClusterAccess(
+		newObject("Namespace", "default", "", nil),
+		newObject("Namespace", "other", "", nil),
+		newObject("ConfigMap", "config", "default", map[string]interface{}{"key": "value"}),
+		newObject("ConfigMap", "config", "other", map[string]interface{}{"key": "other"}),
+	)
+	plc := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy-raw", Namespace: "managed"},
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Inform,
+			ObjectTemplatesRaw: `{{ range (list "default" "other") }}
+- complianceType: musthave
+  objectDefinition:
+    apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: config
+      namespace: {{ . }}
+    data:
+      key: value
+{{ end }}`,
+		},
+		Status: policyv1.ConfigurationPolicyStatus{
+			CompliancyDetails: []policyv1.TemplateStatus{{}, {}, {ComplianceState: policyv1.NonCompliant}},
+		},
+	}
+
+	// a template is generated for each namespace, and the status of the removed templates is dropped
+	result, err := Evaluate(context.TODO(), plc, access)
+	assert.Nil(t, err)
+	assert.Equal(t, policyv1.NonCompliant, result.ComplianceState)
+	assert.Len(t, result.Templates, 2)
+	assert.Equal(t, policyv1.Compliant, result.Templates[0].ComplianceState)
+	assert.Equal(t, policyv1.NonCompliant, result.Templates[1].ComplianceState)
+
+	plc.Spec.ObjectTemplatesRaw = `{{ range (list "default") }}- complianceType: {{ end }`
+	result, err = Evaluate(context.TODO(), plc, access)
+	assert.Nil(t, err)
+	assert.Equal(t, policyv1.NonCompliant, result.ComplianceState)
+	assert.Equal(t, "Error processing template", result.Templates[0].Reason)
+	assert.Len(t, result.Templates, 1)
+
+	// the generated templates can't be evaluated without a cluster
+	plc.Status = policyv1.ConfigurationPolicyStatus{}
+	result = EvaluateObjects(plc, nil)
+	assert.Len(t, result.Templates, 1)
+	assert.Equal(t, policyv1.UnknownCompliancy, result.Templates[0].ComplianceState)
+	assert.Equal(t, "Template not resolved", result.Templates[0].Reason)
+}
+
+func TestEvaluateObjectTemplatesRawNotResolvedTwice(t *testing.T) {
+	const injected = `{{ fromSecret "default" "creds" "password" }}`
+	access := newClusterAccess(
+		newObject("Namespace", "default", "", nil),
+		newObject("ConfigMap", "config", "default", map[string]interface{}{"key": injected}),
+	)
+	access.Kube = fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "source", Namespace: "default"},
+			Data:       map[string]string{"key": injected},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "creds", Namespace: "default"},
+			Data:       map[string][]byte{"password": []byte("hunter22")},
+		},
+	)
+	plc := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy-raw-injection", Namespace: "default"},
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Inform,
+			ObjectTemplatesRaw: `- complianceType: musthave
+  objectDefinition:
+    apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: config
+      namespace: default
+    data:
+      key: {{ fromConfigMap "default" "source" "key" | toJSON }}`,
+		},
+	}
+
+	// the looked up value is compared as is, the template it holds is not resolved
+	result, err := Evaluate(context.TODO(), plc, access)
+	assert.Nil(t, err)
+	assert.Len(t, result.Templates, 1)
+	assert.Equal(t, policyv1.Compliant, result.Templates[0].ComplianceState)
+}
+
+func TestEvaluateTemplateNamespaceSelector(t *testing.T) {
+	access := newClusterAccess(
+		newObject("Namespace", "default", "", nil),
+		newObject("Namespace", "other", "", nil),
+		newObject("ConfigMap", "config", "default", map[string]interface{}{"key": "value"}),
+		newObject("ConfigMap", "config", "other", map[string]interface{}{"key": "other"}),
+	)
+	plc := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy-template-namespaces", Namespace: "managed"},
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Inform,
+			NamespaceSelector: policyv1.Target{Include: []string{"default"}},
+			ObjectTemplates: []*policyv1.ObjectTemplate{
+				{
+					ComplianceType: policyv1.MustHave,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+						`"metadata": {"name": "config"}, "data": {"key": "value"}}`)},
+				},
+				{
+					ComplianceType: policyv1.MustHave,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+						`"metadata": {"name": "config"}, "data": {"key": "other"}}`)},
+					NamespaceSelector: &policyv1.Target{Include: []string{"*"}, Exclude: []string{"default"}},
+				},
+			},
+		},
+	}
+
+	// the second template only checks the namespaces of its own selector
+	result, err := Evaluate(context.TODO(), plc, access)
+	assert.Nil(t, err)
+	assert.Equal(t, policyv1.Compliant, result.ComplianceState)
+	assert.Len(t, result.Templates, 2)
+	assert.Len(t, result.RelatedObjects, 2)
+}
+
+func TestEvaluateKeepsRelatedObjectsOfFailingTemplates(t *testing.T) {
+	access := newClusterAccess(newObject("Namespace", "default", "", nil))
+	properties := &policyv1.ObjectProperties{CreatedByPolicy: true, UID: "1234"}
+	newRelated := func(name string) policyv1.RelatedObject {
+		return policyv1.RelatedObject{
+			Object: policyv1.ObjectResource{Kind: "configmaps", APIVersion: "v1",
+				Metadata: policyv1.ObjectMetadata{Name: name, Namespace: "default"}},
+			Compliant:  string(policyv1.Compliant),
+			Reason:     "Resource found as expected",
+			Properties: properties,
+		}
+	}
+	plc := &policyv1.ConfigurationPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: "policy-configmap", Namespace: "managed"},
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Enforce,
+			NamespaceSelector: policyv1.Target{Include: []string{"default"}},
+			ObjectTemplates: []*policyv1.ObjectTemplate{{
+				ComplianceType: policyv1.MustHave,
+				ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+					`"metadata": {"name": "templated"}, "data": {"key": "{{ notAFunction }}"}}`)},
+			}, {
+				ComplianceType: policyv1.MustHave,
+				NamespaceSelector: &policyv1.Target{MatchExpressions: []metav1.LabelSelectorRequirement{{
+					Key: "env", Operator: "NotAnOperator"}}},
+				ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+					`"metadata": {"name": "selected"}}`)},
+			}},
+		},
+		Status: policyv1.ConfigurationPolicyStatus{
+			RelatedObjects: []policyv1.RelatedObject{newRelated("selected"), newRelated("templated")},
+		},
+	}
+
+	// the objects created by the templates that failed are still related to the policy, so they are pruned with it
+	result, err := Evaluate(context.TODO(), plc, access)
+	assert.Nil(t, err)
+	assert.Equal(t, "Error processing template", result.Templates[0].Reason)
+	assert.Equal(t, "Invalid namespace selector", result.Templates[1].Reason)
+	assert.Equal(t, []policyv1.RelatedObject{newRelated("selected"), newRelated("templated")}, result.RelatedObjects)
+}
+
+func TestEvaluateObjects(t *testing.T) {
+	objects := []unstructured.Unstructured{
+		*newObject("Namespace", "default", "", nil),
+		*newObject("Namespace", "other", "", nil),
+		*newObject("ConfigMap", "match", "default", map[string]interface{}{"key": "value"}),
+		*newObject("ConfigMap", "mismatch", "default", map[string]interface{}{"key": "other"}),
+		*newObject("ConfigMap", "forbidden", "other", nil),
+	}
+	plc := &policyv1.ConfigurationPolicy{
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Enforce,
+			NamespaceSelector: policyv1.Target{Include: []string{"*"}},
+			ObjectTemplates: []*policyv1.ObjectTemplate{
+				{
+					ComplianceType: policyv1.MustHave,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+						`"metadata": {"name": "match", "namespace": "default"}, "data": {"key": "value"}}`)},
+				},
+				{
+					ComplianceType: policyv1.MustHave,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+						`"metadata": {"name": "mismatch", "namespace": "default"}, "data": {"key": "value"}}`)},
+				},
+				{
+					ComplianceType: policyv1.MustNotHave,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+						`"metadata": {"name": "forbidden"}}`)},
+				},
+				{
+					ComplianceType: policyv1.MustHave,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "Namespace",` +
+						`"metadata": {"name": "default"}}`)},
+				},
+				{
+					ComplianceType: policyv1.MustHave,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+						`"metadata": {"name": "{{ fromClusterClaim \"name\" }}", "namespace": "default"}}`)},
+				},
+			},
+		},
+	}
+
+	// the enforced policy is evaluated as an inform policy
+	result := EvaluateObjects(plc, objects)
+	assert.Empty(t, plc.Status.CompliancyDetails, "the policy must not be modified")
+	assert.Equal(t, policyv1.NonCompliant, result.ComplianceState)
+	assert.Len(t, result.Templates, 5)
+	assert.Equal(t, policyv1.Compliant, result.Templates[0].ComplianceState)
+	assert.Equal(t, policyv1.NonCompliant, result.Templates[1].ComplianceState)
+	assert.Equal(t, "configmaps not found: [mismatch] in namespace default found but not as specified",
+		result.Templates[1].Message)
+	assert.Equal(t, policyv1.NonCompliant, result.Templates[2].ComplianceState)
+	assert.Equal(t, "configmaps found: [forbidden] in namespace other", result.Templates[2].Message)
+	assert.Equal(t, policyv1.Compliant, result.Templates[3].ComplianceState)
+	assert.Equal(t, policyv1.UnknownCompliancy, result.Templates[4].ComplianceState)
+	assert.Equal(t, "Template not resolved", result.Templates[4].Reason)
+
+	// the related objects report why an object does not match
+	for _, related := range result.RelatedObjects {
+		if related.Object.Metadata.Name == "mismatch" {
+			assert.Equal(t, []policyv1.FieldDifference{{Path: "data.key", Actual: `"other"`, Expected: `"value"`}},
+				related.Differences)
+		}
+	}
+}
+
+func TestEvaluateObjectsMissingNamespace(t *testing.T) {
+	plc := &policyv1.ConfigurationPolicy{
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Inform,
+			ObjectTemplates: []*policyv1.ObjectTemplate{{
+				ComplianceType: policyv1.MustHave,
+				ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+					`"metadata": {"name": "match"}}`)},
+			}},
+		},
+	}
+
+	result := EvaluateObjects(plc, []unstructured.Unstructured{*newObject("ConfigMap", "match", "default", nil)})
+	assert.Equal(t, policyv1.NonCompliant, result.ComplianceState)
+	assert.Equal(t, "K8s missing namespace", result.Templates[0].Reason)
+}
+
+func TestEvaluateObjectsTemplateNamespaceSelector(t *testing.T) {
+	ops := newObject("Namespace", "ops-1", "", nil)
+	ops.SetLabels(map[string]string{"team": "ops"})
+	objects := []unstructured.Unstructured{
+		*newObject("Namespace", "app-1", "", nil),
+		*ops,
+		*newObject("ConfigMap", "app-config", "app-1", nil),
+		*newObject("ConfigMap", "ops-config", "ops-1", nil),
+	}
+	plc := &policyv1.ConfigurationPolicy{
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Inform,
+			NamespaceSelector: policyv1.Target{Include: []string{"app-*"}},
+			ObjectTemplates: []*policyv1.ObjectTemplate{
+				{
+					ComplianceType: policyv1.MustHave,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+						`"metadata": {"name": "app-config"}}`)},
+				},
+				{
+					ComplianceType:    policyv1.MustHave,
+					NamespaceSelector: &policyv1.Target{MatchLabels: map[string]string{"team": "ops"}},
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+						`"metadata": {"name": "ops-config"}}`)},
+				},
+				{
+					ComplianceType:    policyv1.MustHave,
+					NamespaceSelector: &policyv1.Target{MatchLabels: map[string]string{"team": "not valid"}},
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "ConfigMap",` +
+						`"metadata": {"name": "ops-config"}}`)},
+				},
+			},
+		},
+	}
+
+	// each template is checked in its own namespaces
+	result := EvaluateObjects(plc, objects)
+	assert.Len(t, result.Templates, 3)
+	assert.Equal(t, policyv1.Compliant, result.Templates[0].ComplianceState)
+	assert.Equal(t, policyv1.Compliant, result.Templates[1].ComplianceState)
+	assert.Equal(t, policyv1.NonCompliant, result.Templates[2].ComplianceState)
+	assert.Equal(t, "Invalid namespace selector", result.Templates[2].Reason)
+}
+
+func TestEvaluateObjectsObjectSelector(t *testing.T) {
+	objects := []unstructured.Unstructured{}
+	for _, pod := range []struct{ name, app, phase, owner string }{
+		{"payments-1", "payments", "Running", "payments-rs"},
+		{"payments-2", "payments", "Pending", "payments-rs"},
+		{"web", "web", "Pending", "web-rs"},
+	} {
+		object := newObject("Pod", pod.name, "default", nil)
+		object.SetLabels(map[string]string{"app": pod.app})
+		object.SetOwnerReferences([]metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet",
+			Name: pod.owner}})
+		object.Object["status"] = map[string]interface{}{"phase": pod.phase}
+		objects = append(objects, *object)
+	}
+	unnamedPod := runtime.RawExtension{Raw: []byte(`{"apiVersion": "v1", "kind": "Pod",` +
+		`"metadata": {"namespace": "default"}}`)}
+	plc := &policyv1.ConfigurationPolicy{
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Inform,
+			ObjectTemplates: []*policyv1.ObjectTemplate{
+				{
+					ComplianceType: policyv1.MustNotHave,
+					ObjectSelector: &policyv1.ObjectSelector{
+						MatchLabels:   map[string]string{"app": "payments"},
+						FieldSelector: "status.phase=Pending",
+					},
+					ObjectDefinition: unnamedPod,
+				},
+				{
+					ComplianceType: policyv1.MustNotHave,
+					ObjectSelector: &policyv1.ObjectSelector{Owner: &policyv1.OwnerSelector{Kind: "ReplicaSet",
+						Name: "other-rs"}},
+					ObjectDefinition: unnamedPod,
+				},
+				{
+					ComplianceType:   policyv1.MustHave,
+					ObjectSelector:   &policyv1.ObjectSelector{FieldSelector: "status.phase"},
+					ObjectDefinition: unnamedPod,
+				},
+			},
+		},
+	}
+
+	// only the selected pods are compared with the templates
+	result := EvaluateObjects(plc, objects)
+	assert.Len(t, result.Templates, 3)
+	assert.Equal(t, policyv1.NonCompliant, result.Templates[0].ComplianceState)
+	assert.Equal(t, "pods found: [payments-2] in namespace default", result.Templates[0].Message)
+	assert.Equal(t, policyv1.Compliant, result.Templates[1].ComplianceState)
+	assert.Equal(t, "Invalid object selector", result.Templates[2].Reason)
+}
+
+func TestEvaluateObjectsAssertions(t *testing.T) {
+	objects := []unstructured.Unstructured{}
+	for name, replicas := range map[string]int64{"payments": 3, "web": 1} {
+		object := newObject("Deployment", name, "default", nil)
+		object.SetAPIVersion("apps/v1")
+		object.Object["spec"] = map[string]interface{}{"replicas": replicas}
+		objects = append(objects, *object)
+	}
+	replicas := []policyv1.Assertion{{Path: ".spec.replicas", Operator: policyv1.AssertGreaterThanOrEqual,
+		Value: "2"}}
+	plc := &policyv1.ConfigurationPolicy{
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: policyv1.Inform,
+			ObjectTemplates: []*policyv1.ObjectTemplate{
+				{
+					ComplianceType: policyv1.MustHave,
+					Assertions:     replicas,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "apps/v1", ` +
+						`"kind": "Deployment", "metadata": {"name": "web", "namespace": "default"}}`)},
+				},
+				{
+					ComplianceType: policyv1.MustHave,
+					Assertions:     replicas,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "apps/v1", ` +
+						`"kind": "Deployment", "metadata": {"namespace": "default"}}`)},
+				},
+				{
+					ComplianceType: policyv1.MustHave,
+					Assertions:     replicas,
+					ObjectDefinition: runtime.RawExtension{Raw: []byte(`{"apiVersion": "apps/v1", ` +
+						`"kind": "Deployment", "metadata": {"name": "payments", "namespace": "default"}}`)},
+				},
+			},
+		},
+	}
+
+	result := EvaluateObjects(plc, objects)
+	assert.Equal(t, policyv1.NonCompliant, result.ComplianceState)
+	assert.Len(t, result.Templates, 3)
+	assert.Equal(t, policyv1.NonCompliant, result.Templates[0].ComplianceState)
+	assert.Equal(t, "deployments not found: [web] in namespace default found but failed the assertions",
+		result.Templates[0].Message)
+	// only the deployment failing the assertion is noncompliant
+	assert.Equal(t, policyv1.NonCompliant, result.Templates[1].ComplianceState)
+	assert.Equal(t, "deployments not found: [web] in namespace default found but failed the assertions",
+		result.Templates[1].Message)
+	assert.Equal(t, policyv1.Compliant, result.Templates[2].ComplianceState)
+
+	failures := map[string][]policyv1.AssertionFailure{}
+	for _, related := range result.RelatedObjects {
+		if related.Compliant == string(policyv1.NonCompliant) {
+			assert.Equal(t, "Resource found but failed the assertions", related.Reason)
+			failures[related.Object.Metadata.Name] = related.FailedAssertions
+		}
+	}
+	assert.Equal(t, map[string][]policyv1.AssertionFailure{"web": {{Path: "spec.replicas",
+		Operator: policyv1.AssertGreaterThanOrEqual, Value: "2", Actual: "1"}}}, failures)
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang/glog"
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/open-cluster-management/config-policy-controller/pkg/common"
+	"github.com/open-cluster-management/config-policy-controller/pkg/common/templates"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// ValidatePath is the path the ConfigurationPolicy validating webhook is served at
+const ValidatePath = "/validate-policy-open-cluster-management-io-v1-configurationpolicy"
+
+// AddToManager registers the ConfigurationPolicy validating webhook on the webhook server of the manager
+func AddToManager(mgr manager.Manager) error {
+	mgr.GetWebhookServer().Register(ValidatePath, &webhook.Admission{
+		Handler: &configurationPolicyValidator{mapper: mgr.GetRESTMapper()},
+	})
+	return nil
+}
+
+// configurationPolicyValidator rejects the policies that would fail when they are evaluated
+type configurationPolicyValidator struct {
+	mapper  meta.RESTMapper
+	decoder *admission.Decoder
+}
+
+// InjectDecoder is called by the webhook server to set the decoder of the admission requests
+func (v *configurationPolicyValidator) InjectDecoder(d *admission.Decoder) error {
+	v.decoder = d
+	return nil
+}
+
+// Handle validates the ConfigurationPolicy of a create or update request
+func (v *configurationPolicyValidator) Handle(ctx context.Context, req admission.Request) admission.Response {
+	plc := &policyv1.ConfigurationPolicy{}
+	if err := v.decoder.Decode(req, plc); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	errs := validateConfigurationPolicy(plc, v.mapper)
+	if len(errs) > 0 {
+		glog.V(2).Infof("Rejecting the configuration policy %v/%v: %v", req.Namespace, req.Name, errs)
+		return admission.Denied(strings.Join(errs, "; "))
+	}
+	return admission.Allowed("")
+}
+
+// validateConfigurationPolicy returns a message for each problem that would make the evaluation of the
+// policy fail, the messages of an object template name its index
+func validateConfigurationPolicy(plc *policyv1.ConfigurationPolicy, mapper meta.RESTMapper) []string {
+	errs := []string{}
+	switch strings.ToLower(string(plc.Spec.RemediationAction)) {
+	case strings.ToLower(string(policyv1.Inform)), strings.ToLower(string(policyv1.Enforce)),
+		strings.ToLower(string(policyv1.DryRun)):
+	case "":
+		errs = append(errs, "spec.remediationAction must be set to inform, enforce or dryrun")
+	default:
+		errs = append(errs, fmt.Sprintf("spec.remediationAction `%v` must be one of inform, enforce or dryrun",
+			plc.Spec.RemediationAction))
+	}
+	hasNamespaceSelector := selectsNamespaces(plc.Spec.NamespaceSelector) || len(plc.Spec.LabelSelector) > 0
+
+	if plc.Spec.ObjectTemplatesRaw != "" {
+		if len(plc.Spec.ObjectTemplates) > 0 {
+			errs = append(errs, "object-templates and object-templates-raw can not both be set")
+		}
+		// the object templates are generated on the managed cluster, only the template syntax is validated
+		if err := templates.ValidateRawTemplate(plc.Spec.ObjectTemplatesRaw); err != nil {
+			errs = append(errs, fmt.Sprintf("object-templates-raw: the template could not be parsed: %v", err))
+		}
+	}
+	for i, objectT := range plc.Spec.ObjectTemplates {
+		prefix := fmt.Sprintf("object-templates[%v]", i)
+		if objectT == nil {
+			errs = append(errs, prefix+" must not be empty")
+			continue
+		}
+		complianceType := strings.ToLower(string(objectT.ComplianceType))
+		switch complianceType {
+		case strings.ToLower(string(policyv1.MustHave)), strings.ToLower(string(policyv1.MustOnlyHave)),
+			strings.ToLower(string(policyv1.MustNotHave)):
+		default:
+			errs = append(errs, fmt.Sprintf("%v: complianceType `%v` must be one of musthave, mustnothave or "+
+				"mustonlyhave", prefix, objectT.ComplianceType))
+		}
+		if objectT.FieldLevel && complianceType != strings.ToLower(string(policyv1.MustNotHave)) {
+			errs = append(errs, prefix+": fieldLevel is only supported with the mustnothave complianceType")
+		}
+		if len(objectT.Assertions) > 0 && complianceType == strings.ToLower(string(policyv1.MustNotHave)) {
+			errs = append(errs, prefix+": assertions are not supported with the mustnothave complianceType")
+		}
+		for j, assertion := range objectT.Assertions {
+			if err := common.ValidateAssertion(assertion); err != nil {
+				errs = append(errs, fmt.Sprintf("%v: assertions[%v]: %v", prefix, j, err))
+			}
+		}
+
+		templateSelectsNamespaces := hasNamespaceSelector
+		if objectT.NamespaceSelector != nil {
+			if _, err := common.NamespaceLabelSelector(*objectT.NamespaceSelector, nil); err != nil {
+				errs = append(errs, fmt.Sprintf("%v: the namespaceSelector is not valid: %v", prefix, err))
+			}
+			templateSelectsNamespaces = selectsNamespaces(*objectT.NamespaceSelector)
+		}
+		if objectT.ObjectSelector != nil {
+			if _, _, err := common.ObjectSelectors(*objectT.ObjectSelector); err != nil {
+				errs = append(errs, fmt.Sprintf("%v: the objectSelector is not valid: %v", prefix, err))
+			}
+		}
+
+		raw := objectT.ObjectDefinition.Raw
+		if templates.HasTemplate(string(raw)) {
+			// the template is resolved on the managed cluster, only its syntax is validated
+			var blob interface{}
+			if err := json.Unmarshal(raw, &blob); err != nil {
+				errs = append(errs, fmt.Sprintf("%v: the objectDefinition could not be decoded: %v", prefix, err))
+				continue
+			}
+			if err := templates.ValidateTemplate(blob); err != nil {
+				errs = append(errs, fmt.Sprintf("%v: the template could not be parsed: %v", prefix, err))
+			}
+			continue
+		}
+		obj, gvk, err := unstructured.UnstructuredJSONScheme.Decode(raw, nil, nil)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%v: the objectDefinition could not be decoded: %v", prefix, err))
+			continue
+		}
+		if objectT.ObjectSelector != nil && obj.(*unstructured.Unstructured).GetName() != "" {
+			errs = append(errs, prefix+": objectSelector is only supported when the objectDefinition has no name")
+		}
+		if templateSelectsNamespaces || obj.(*unstructured.Unstructured).GetNamespace() != "" || mapper == nil {
+			continue
+		}
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			// the kind may be installed after the policy, it is reported when the policy is evaluated
+			glog.V(2).Infof("Skipping the namespace validation of %v: %v", prefix, err)
+			continue
+		}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			errs = append(errs, fmt.Sprintf("%v: the %v kind is namespaced, the objectDefinition must set "+
+				"metadata.namespace or the object template or the policy must have a namespaceSelector", prefix,
+				gvk.Kind))
+		}
+	}
+	return errs
+}
+
+// selectsNamespaces returns true when the namespace selector includes namespaces by name or by label
+func selectsNamespaces(selector policyv1.Target) bool {
+	return len(selector.Include) > 0 || len(selector.MatchLabels) > 0 || len(selector.MatchExpressions) > 0
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package webhook
+
+import (
+	"testing"
+
+	policyv1 "github.com/open-cluster-management/config-policy-controller/pkg/apis/policy/v1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newPolicy(remediationAction policyv1.RemediationAction,
+	objectTemplates ...*policyv1.ObjectTemplate) *policyv1.ConfigurationPolicy {
+	return &policyv1.ConfigurationPolicy{
+		Spec: policyv1.ConfigurationPolicySpec{
+			RemediationAction: remediationAction,
+			ObjectTemplates:   objectTemplates,
+		},
+	}
+}
+
+func newObjectTemplate(complianceType policyv1.ComplianceType, objectDefinition string) *policyv1.ObjectTemplate {
+	return &policyv1.ObjectTemplate{
+		ComplianceType:   complianceType,
+		ObjectDefinition: runtime.RawExtension{Raw: []byte(objectDefinition)},
+	}
+}
+
+func newRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	return mapper
+}
+
+const (
+	podInDefault = `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "nginx", "namespace": "default"}}`
+	podNoNs      = `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "nginx"}}`
+	namespace    = `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "test"}}`
+)
+
+func TestValidateValidPolicy(t *testing.T) {
+	plc := newPolicy("enforce",
+		newObjectTemplate("musthave", podInDefault),
+		newObjectTemplate("mustonlyhave", namespace),
+		newObjectTemplate("MustNotHave", `{"apiVersion": "v1", "kind": "Unknown", "metadata": {"name": "a"}}`),
+		newObjectTemplate("musthave",
+			`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "a", "namespace": "default"},`+
+				`"data": {"key": "{{ fromSecret \"default\" \"secret\" \"key\" }}"}}`),
+	)
+	assert.Empty(t, validateConfigurationPolicy(plc, newRESTMapper()))
+
+	// a namespaced kind without a namespace is valid when the policy selects the namespaces
+	plc = newPolicy("inform", newObjectTemplate("musthave", podNoNs))
+	plc.Spec.NamespaceSelector.Include = []string{"default"}
+	assert.Empty(t, validateConfigurationPolicy(plc, newRESTMapper()))
+}
+
+func TestValidateInvalidPolicy(t *testing.T) {
+	fieldLevel := newObjectTemplate("musthave", podInDefault)
+	fieldLevel.FieldLevel = true
+	plc := newPolicy("",
+		newObjectTemplate("musthave", `{"apiVersion": "v1", "metadata": {"name": "nginx"}}`),
+		newObjectTemplate("shouldhave", podInDefault),
+		newObjectTemplate("musthave", podNoNs),
+		newObjectTemplate("musthave", `{"kind": "ConfigMap", "data": {"key": "{{ notAFunction }}"}}`),
+		fieldLevel,
+	)
+
+	errs := validateConfigurationPolicy(plc, newRESTMapper())
+	assert.Len(t, errs, 6)
+	assert.Equal(t, "spec.remediationAction must be set to inform, enforce or dryrun", errs[0])
+	assert.Contains(t, errs[1], "object-templates[0]: the objectDefinition could not be decoded")
+	assert.Equal(t, "object-templates[1]: complianceType `shouldhave` must be one of musthave, mustnothave or "+
+		"mustonlyhave", errs[2])
+	assert.Equal(t, "object-templates[2]: the Pod kind is namespaced, the objectDefinition must set "+
+		"metadata.namespace or the object template or the policy must have a namespaceSelector", errs[3])
+	assert.Contains(t, errs[4], "object-templates[3]: the template could not be parsed")
+	assert.Contains(t, errs[4], `function "notAFunction" not defined`)
+	assert.Equal(t, "object-templates[4]: fieldLevel is only supported with the mustnothave complianceType",
+		errs[5])
+
+	plc = newPolicy("remove")
+	assert.Equal(t, []string{"spec.remediationAction `remove` must be one of inform, enforce or dryrun"},
+		validateConfigurationPolicy(plc, newRESTMapper()))
+}
+
+func TestValidateObjectTemplatesRaw(t *testing.T) {
+	plc := newPolicy("inform")
+	plc.Spec.ObjectTemplatesRaw = `{{ range (lookup "v1" "Namespace" "" "").items }}
+- complianceType: musthave
+  objectDefinition:
+    apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: config
+      namespace: {{ .metadata.name }}
+{{ end }}`
+	assert.Empty(t, validateConfigurationPolicy(plc, newRESTMapper()))
+
+	plc = newPolicy("inform", newObjectTemplate("musthave", namespace))
+	plc.Spec.ObjectTemplatesRaw = `{{ range notAFunction }}{{ end }}`
+	errs := validateConfigurationPolicy(plc, newRESTMapper())
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "object-templates and object-templates-raw can not both be set", errs[0])
+	assert.Contains(t, errs[1], "object-templates-raw: the template could not be parsed")
+	assert.Contains(t, errs[1], `function "notAFunction" not defined`)
+}
+
+func TestValidateObjectTemplateNamespaceSelector(t *testing.T) {
+	// the namespaces of the pod are selected by its template
+	selected := newObjectTemplate("musthave", podNoNs)
+	selected.NamespaceSelector = &policyv1.Target{Include: []string{"app-*"}}
+	plc := newPolicy("inform", selected)
+	assert.Empty(t, validateConfigurationPolicy(plc, newRESTMapper()))
+
+	// the selector of the template replaces the one of the policy
+	excluded := newObjectTemplate("musthave", podNoNs)
+	excluded.NamespaceSelector = &policyv1.Target{Exclude: []string{"kube-*"}}
+	invalid := newObjectTemplate("musthave", podInDefault)
+	invalid.NamespaceSelector = &policyv1.Target{MatchExpressions: []metav1.LabelSelectorRequirement{
+		{Key: "team", Operator: "Equals", Values: []string{"ops"}},
+	}}
+	plc = newPolicy("inform", excluded, invalid)
+	plc.Spec.NamespaceSelector.Include = []string{"default"}
+	errs := validateConfigurationPolicy(plc, newRESTMapper())
+	assert.Len(t, errs, 2)
+	assert.Equal(t, "object-templates[0]: the Pod kind is namespaced, the objectDefinition must set "+
+		"metadata.namespace or the object template or the policy must have a namespaceSelector", errs[0])
+	assert.Contains(t, errs[1], "object-templates[1]: the namespaceSelector is not valid")
+}
+
+func TestValidateObjectSelector(t *testing.T) {
+	selected := newObjectTemplate("musthave", `{"apiVersion": "v1", "kind": "Pod", "metadata": {"namespace": "default"}}`)
+	selected.ObjectSelector = &policyv1.ObjectSelector{
+		MatchLabels:   map[string]string{"app": "payments"},
+		FieldSelector: "status.phase=Running",
+		Owner:         &policyv1.OwnerSelector{Kind: "ReplicaSet", Name: "payments-5d8f"},
+	}
+	assert.Empty(t, validateConfigurationPolicy(newPolicy("inform", selected), newRESTMapper()))
+
+	const unnamed = `{"apiVersion": "v1", "kind": "Namespace"}`
+	named := newObjectTemplate("musthave", podInDefault)
+	named.ObjectSelector = &policyv1.ObjectSelector{MatchLabels: map[string]string{"app": "payments"}}
+	invalidLabels := newObjectTemplate("musthave", unnamed)
+	invalidLabels.ObjectSelector = &policyv1.ObjectSelector{MatchLabels: map[string]string{"app": "not valid"}}
+	invalidFields := newObjectTemplate("musthave", unnamed)
+	invalidFields.ObjectSelector = &policyv1.ObjectSelector{FieldSelector: "status.phase"}
+	noOwnerKind := newObjectTemplate("musthave", unnamed)
+	noOwnerKind.ObjectSelector = &policyv1.ObjectSelector{Owner: &policyv1.OwnerSelector{Name: "owner"}}
+	errs := validateConfigurationPolicy(newPolicy("inform", named, invalidLabels, invalidFields, noOwnerKind),
+		newRESTMapper())
+	assert.Len(t, errs, 4)
+	assert.Equal(t, "object-templates[0]: objectSelector is only supported when the objectDefinition has no name",
+		errs[0])
+	assert.Contains(t, errs[1], "object-templates[1]: the objectSelector is not valid: the label selector")
+	assert.Contains(t, errs[2], "object-templates[2]: the objectSelector is not valid: the field selector")
+	assert.Equal(t, "object-templates[3]: the objectSelector is not valid: the owner must have a kind", errs[3])
+}
+
+func TestValidateAssertions(t *testing.T) {
+	asserted := newObjectTemplate("musthave", podInDefault)
+	asserted.Assertions = []policyv1.Assertion{
+		{Path: ".spec.containers[*].resources.limits.memory", Operator: policyv1.AssertExists},
+		{Path: ".metadata.labels.app", Operator: "hasprefix", Value: "payments"},
+	}
+	assert.Empty(t, validateConfigurationPolicy(newPolicy("enforce", asserted), newRESTMapper()))
+
+	invalid := newObjectTemplate("musthave", podInDefault)
+	invalid.Assertions = []policyv1.Assertion{
+		{Path: "spec.replicas", Operator: policyv1.AssertExists},
+		{Path: ".spec.replicas", Operator: policyv1.AssertGreaterThanOrEqual, Value: "two"},
+	}
+	mustNotHave := newObjectTemplate("mustnothave", podInDefault)
+	mustNotHave.Assertions = []policyv1.Assertion{{Path: ".spec.replicas", Operator: policyv1.AssertExists}}
+	errs := validateConfigurationPolicy(newPolicy("inform", invalid, mustNotHave), newRESTMapper())
+	assert.Equal(t, []string{
+		`object-templates[0]: assertions[0]: the path "spec.replicas" must start with a . or a [`,
+		`object-templates[0]: assertions[1]: the value "two" of the GreaterThanOrEqual operator must be a number ` +
+			`or a quantity`,
+		"object-templates[1]: assertions are not supported with the mustnothave complianceType",
+	}, errs)
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package e2e
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-cluster-management/config-policy-controller/test/utils"
+)
+
+const case14ConfigPolicyNameNS string = "policy-labeled-ns-create"
+const case14ConfigPolicyNameCM string = "policy-configmap-labeled-ns"
+const case14NSYaml string = "../resources/case14_namespace_labels/case14_create_ns.yaml"
+const case14CMYaml string = "../resources/case14_namespace_labels/case14_configmap_labels.yaml"
+const case14LabeledNS string = "case14-tenant-a8f3k"
+const case14OtherNS string = "case14-tenant-q2x9z"
+const case14ConfigMapName string = "case14-tenant-config"
+
+var _ = Describe("Test namespace selection by label", func() {
+	Describe("Create a policy selecting namespaces by label on managed cluster in ns:"+testNamespace, func() {
+		It("should create the labeled namespaces", func() {
+			By("Creating " + case14ConfigPolicyNameNS + " on managed")
+			utils.Kubectl("apply", "-f", case14NSYaml, "-n", testNamespace)
+			plc := utils.GetWithTimeout(clientManagedDynamic, gvrConfigPolicy, case14ConfigPolicyNameNS, testNamespace, true, defaultTimeoutSeconds)
+			Expect(plc).NotTo(BeNil())
+			Eventually(func() interface{} {
+				managedPlc := utils.GetWithTimeout(clientManagedDynamic, gvrConfigPolicy, case14ConfigPolicyNameNS, testNamespace, true, defaultTimeoutSeconds)
+				return utils.GetComplianceState(managedPlc)
+			}, defaultTimeoutSeconds, 1).Should(Equal("Compliant"))
+		})
+		It("should only create the configmap in the namespaces matching the labels", func() {
+			By("Creating " + case14ConfigPolicyNameCM + " on managed")
+			utils.Kubectl("apply", "-f", case14CMYaml, "-n", testNamespace)
+			plc := utils.GetWithTimeout(clientManagedDynamic, gvrConfigPolicy, case14ConfigPolicyNameCM, testNamespace, true, defaultTimeoutSeconds)
+			Expect(plc).NotTo(BeNil())
+			Eventually(func() interface{} {
+				managedPlc := utils.GetWithTimeout(clientManagedDynamic, gvrConfigPolicy, case14ConfigPolicyNameCM, testNamespace, true, defaultTimeoutSeconds)
+				return utils.GetComplianceState(managedPlc)
+			}, defaultTimeoutSeconds, 1).Should(Equal("Compliant"))
+			cm := utils.GetWithTimeout(clientManagedDynamic, gvrConfigMap, case14ConfigMapName, case14LabeledNS, true, defaultTimeoutSeconds)
+			Expect(cm).NotTo(BeNil())
+			cm = utils.GetWithTimeout(clientManagedDynamic, gvrConfigMap, case14ConfigMapName, case14OtherNS, false, defaultTimeoutSeconds)
+			Expect(cm).To(BeNil())
+		})
+	})
+})
@@ -0,0 +1,37 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package e2e
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-cluster-management/config-policy-controller/test/utils"
+)
+
+const case15ConfigPolicyName string = "policy-configmap-dry-run"
+const case15PolicyYaml string = "../resources/case15_dry_run/case15_configmap_dry_run.yaml"
+const case15ConfigMapName string = "case15-dry-run-config"
+
+var _ = Describe("Test dry run enforcement", func() {
+	Describe("Create a dryrun policy on managed cluster in ns:"+testNamespace, func() {
+		It("should report the configmap it would create without creating it", func() {
+			By("Creating " + case15ConfigPolicyName + " on managed")
+			utils.Kubectl("apply", "-f", case15PolicyYaml, "-n", testNamespace)
+			plc := utils.GetWithTimeout(clientManagedDynamic, gvrConfigPolicy, case15ConfigPolicyName, testNamespace, true, defaultTimeoutSeconds)
+			Expect(plc).NotTo(BeNil())
+			Eventually(func() interface{} {
+				managedPlc := utils.GetWithTimeout(clientManagedDynamic, gvrConfigPolicy, case15ConfigPolicyName, testNamespace, true, defaultTimeoutSeconds)
+				return utils.GetComplianceState(managedPlc)
+			}, defaultTimeoutSeconds, 1).Should(Equal("NonCompliant"))
+			Eventually(func() interface{} {
+				managedPlc := utils.GetWithTimeout(clientManagedDynamic, gvrConfigPolicy, case15ConfigPolicyName, testNamespace, true, defaultTimeoutSeconds)
+				details := managedPlc.Object["status"].(map[string]interface{})["compliancyDetails"].([]interface{})
+				conditions := details[0].(map[string]interface{})["conditions"].([]interface{})
+				return conditions[0].(map[string]interface{})["message"]
+			}, defaultTimeoutSeconds, 1).Should(Equal("configmaps [case15-dry-run-config] in namespace default is missing, and would be created (dry run)"))
+			cm := utils.GetWithTimeout(clientManagedDynamic, gvrConfigMap, case15ConfigMapName, "default", false, defaultTimeoutSeconds)
+			Expect(cm).To(BeNil())
+		})
+	})
+})
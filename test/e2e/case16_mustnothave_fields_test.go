@@ -0,0 +1,36 @@
+// Copyright (c) 2021 Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package e2e
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/open-cluster-management/config-policy-controller/test/utils"
+)
+
+const case16ConfigPolicyName string = "policy-configmap-mustnothave-fields"
+const case16PolicyYaml string = "../resources/case16_mustnothave_fields/case16_mustnothave_fields.yaml"
+const case16ConfigMapYaml string = "../resources/case16_mustnothave_fields/case16_configmap.yaml"
+const case16ConfigMapName string = "case16-fields-config"
+
+var _ = Describe("Test field-level mustnothave", func() {
+	Describe("Create a field-level mustnothave policy on managed cluster in ns:"+testNamespace, func() {
+		It("should remove the forbidden fields and keep the configmap", func() {
+			By("Creating " + case16ConfigMapName + " on managed")
+			utils.Kubectl("apply", "-f", case16ConfigMapYaml)
+			By("Creating " + case16ConfigPolicyName + " on managed")
+			utils.Kubectl("apply", "-f", case16PolicyYaml, "-n", testNamespace)
+			plc := utils.GetWithTimeout(clientManagedDynamic, gvrConfigPolicy, case16ConfigPolicyName, testNamespace, true, defaultTimeoutSeconds)
+			Expect(plc).NotTo(BeNil())
+			Eventually(func() interface{} {
+				managedPlc := utils.GetWithTimeout(clientManagedDynamic, gvrConfigPolicy, case16ConfigPolicyName, testNamespace, true, defaultTimeoutSeconds)
+				return utils.GetComplianceState(managedPlc)
+			}, defaultTimeoutSeconds, 1).Should(Equal("Compliant"))
+			cm := utils.GetWithTimeout(clientManagedDynamic, gvrConfigMap, case16ConfigMapName, "default", true, defaultTimeoutSeconds)
+			Expect(cm).NotTo(BeNil())
+			Expect(cm.GetLabels()).To(Equal(map[string]string{"allowed": "true"}))
+			Expect(cm.Object["data"]).To(Equal(map[string]interface{}{"mode": "field-level"}))
+		})
+	})
+})